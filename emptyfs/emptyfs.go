@@ -2,6 +2,8 @@
 package emptyfs
 
 import (
+	"os"
+
 	"github.com/daaku/go.fs"
 	"github.com/daaku/go.fs/fsutil"
 )
@@ -12,7 +14,8 @@ type system struct {
 
 var singleton = system{}
 
-// Provides a File System that is always empty.
+// Provides a File System that is always empty. All write operations fail with
+// a read-only error.
 func New() fs.System {
 	return singleton
 }
@@ -27,7 +30,7 @@ func (s system) Open(name string) (fs.File, error) {
 	if s.fixed != nil {
 		return nil, s.fixed
 	}
-	return nil, fsutil.NewErrNotFound(name)
+	return nil, &fs.PathError{Op: "open", Path: name, Err: fs.ErrNotExist}
 }
 
 func (s system) IsNotExist(err error) bool {
@@ -36,3 +39,41 @@ func (s system) IsNotExist(err error) bool {
 	}
 	return fsutil.IsNotExist(err)
 }
+
+func (s system) Stat(name string) (os.FileInfo, error) {
+	if s.fixed != nil {
+		return nil, s.fixed
+	}
+	return nil, &fs.PathError{Op: "stat", Path: name, Err: fs.ErrNotExist}
+}
+
+func (s system) Create(name string) (fs.File, error) {
+	return nil, &fs.PathError{Op: "open", Path: name, Err: fs.ErrReadOnly}
+}
+
+func (s system) OpenFile(name string, flag int, perm os.FileMode) (fs.File, error) {
+	if fsutil.WantsWrite(flag) {
+		return nil, &fs.PathError{Op: "open", Path: name, Err: fs.ErrReadOnly}
+	}
+	return s.Open(name)
+}
+
+func (s system) Mkdir(name string, perm os.FileMode) error {
+	return &fs.PathError{Op: "mkdir", Path: name, Err: fs.ErrReadOnly}
+}
+
+func (s system) MkdirAll(path string, perm os.FileMode) error {
+	return &fs.PathError{Op: "mkdir", Path: path, Err: fs.ErrReadOnly}
+}
+
+func (s system) Remove(name string) error {
+	return &fs.PathError{Op: "remove", Path: name, Err: fs.ErrReadOnly}
+}
+
+func (s system) RemoveAll(path string) error {
+	return &fs.PathError{Op: "remove", Path: path, Err: fs.ErrReadOnly}
+}
+
+func (s system) Rename(oldname, newname string) error {
+	return &fs.LinkError{Op: "rename", Old: oldname, New: newname, Err: fs.ErrReadOnly}
+}
@@ -2,9 +2,13 @@ package emptyfs_test
 
 import (
 	"errors"
+	"os"
 	"testing"
 
+	"github.com/daaku/go.fs"
 	"github.com/daaku/go.fs/emptyfs"
+	"github.com/daaku/go.fs/fstest"
+	"github.com/daaku/go.fs/fsutil"
 )
 
 func TestAlwaysEmpty(t *testing.T) {
@@ -34,3 +38,45 @@ func TestFixedError(t *testing.T) {
 		t.Fatal("expecting is not exist error")
 	}
 }
+
+func TestReadOnly(t *testing.T) {
+	t.Parallel()
+	s := emptyfs.New()
+	assertReadOnly := func(err error) {
+		if !fsutil.IsReadOnly(err) {
+			t.Fatalf("was expecting read-only error, got %v", err)
+		}
+	}
+	_, err := s.Create("foo")
+	assertReadOnly(err)
+	_, err = s.OpenFile("foo", os.O_WRONLY, 0)
+	assertReadOnly(err)
+	assertReadOnly(s.Mkdir("foo", 0755))
+	assertReadOnly(s.MkdirAll("foo", 0755))
+	assertReadOnly(s.Remove("foo"))
+	assertReadOnly(s.RemoveAll("foo"))
+	assertReadOnly(s.Rename("foo", "bar"))
+	_, err = s.OpenFile("foo", os.O_RDONLY, 0)
+	if !s.IsNotExist(err) {
+		t.Fatalf("was expecting is not exist error, got %v", err)
+	}
+}
+
+func TestStat(t *testing.T) {
+	t.Parallel()
+	s := emptyfs.New()
+	if _, err := fsutil.Stat(s, "foo"); !s.IsNotExist(err) {
+		t.Fatalf("was expecting is not exist error, got %v", err)
+	}
+}
+
+func TestConformance(t *testing.T) {
+	t.Parallel()
+	fstest.TestSystem(t, fstest.Config{
+		New: func(t *testing.T, files map[string]string) (fs.System, string) {
+			return emptyfs.New(), "."
+		},
+		ReadOnly: true,
+		Empty:    true,
+	})
+}
@@ -0,0 +1,10 @@
+//go:build !plan9
+
+package fs
+
+import "syscall"
+
+const (
+	errReadOnly = syscall.EROFS
+	errNotEmpty = syscall.ENOTEMPTY
+)
@@ -0,0 +1,9 @@
+package fs
+
+import "errors"
+
+// Plan 9 reports errors as strings, and has no error numbers for these.
+var (
+	errReadOnly = errors.New("read-only file system")
+	errNotEmpty = errors.New("directory not empty")
+)
@@ -0,0 +1,9 @@
+//go:build !darwin && !dragonfly && !freebsd && !netbsd && !openbsd && !plan9 && !wasip1
+
+package fs
+
+import "syscall"
+
+// Linux, and the systems emulating its error numbers, report missing
+// extended attributes using ENODATA.
+const errNoAttr = syscall.ENODATA
@@ -0,0 +1,8 @@
+//go:build darwin || dragonfly || freebsd || netbsd || openbsd
+
+package fs
+
+import "syscall"
+
+// The BSDs have a dedicated ENOATTR for missing extended attributes.
+const errNoAttr = syscall.ENOATTR
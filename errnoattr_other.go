@@ -0,0 +1,8 @@
+//go:build plan9 || wasip1
+
+package fs
+
+import "errors"
+
+// There is no error number for missing extended attributes here.
+var errNoAttr = errors.New("attribute not found")
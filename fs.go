@@ -28,15 +28,52 @@
 // write APIs. For file systems like realfs, memfs & limitfs this is great
 // since those file systems do in fact provide write APIs. On the other hand
 // zipfs is read-only. For such scenarios the implementation just returns
-// errors when you try to use the write APIs. In practice this doesn't mean
-// much and you can mostly just ignore the write APIs if you live in a read
-// only world and want it's advantages or use the write APIs and not use
-// abstractions like zipfs or pkgfs which don't make much sense with respect
-// to writes.
+// errors matching ErrReadOnly when you try to use the write APIs. In practice
+// this doesn't mean much and you can mostly just ignore the write APIs if you
+// live in a read only world and want it's advantages or use the write APIs and
+// not use abstractions like zipfs or pkgfs which don't make much sense with
+// respect to writes.
+//
+// A note about errors:
+//
+// Implementations return *PathError values carrying the operation, the path
+// and one of the sentinel errors defined here, so callers can use errors.Is
+// and errors.As without knowing which File System they are working with.
 package fs
 
 import (
+	"errors"
+	iofs "io/fs"
 	"os"
+	"syscall"
+	"time"
+)
+
+// PathError records an error and the operation and file path that caused it.
+// It is the same type as os.PathError and io/fs.PathError, so errors.As works
+// regardless of which implementation returned the error.
+type PathError = iofs.PathError
+
+// LinkError records an error during an operation involving two paths, like
+// Rename. It is the same type as os.LinkError.
+type LinkError = os.LinkError
+
+// Sentinel errors carried by the errors returned from File Systems. They are
+// shared with the os, io/fs and syscall packages where possible, so
+// errors.Is(err, ErrNotExist) and errors.Is(err, os.ErrNotExist) are
+// equivalent, and errors from realfs match without any translation.
+var (
+	ErrInvalid      = iofs.ErrInvalid       // invalid argument
+	ErrNotExist     = iofs.ErrNotExist      // file does not exist
+	ErrExist        = iofs.ErrExist         // file already exists
+	ErrPermission   = iofs.ErrPermission    // permission denied
+	ErrClosed       = iofs.ErrClosed        // file already closed
+	ErrReadOnly     = errReadOnly           // file system is read-only
+	ErrNotSupported = errors.ErrUnsupported // operation not supported
+	ErrIsDir        = syscall.EISDIR        // file is a directory
+	ErrNotDir       = syscall.ENOTDIR       // file is not a directory
+	ErrNotEmpty     = errNotEmpty           // directory is not empty
+	ErrNoAttr       = errNoAttr             // extended attribute does not exist
 )
 
 // A File implements access to a single file or directory.
@@ -50,11 +87,8 @@ type File interface {
 	// Chown changes the numeric uid and gid of the named file.
 	Chown(uid, gid int) error
 
-	// Get the owner UID.
-	OwnerGID() (int, error)
-
-	// Get the owner GID.
-	OwnerUID() (int, error)
+	// Ident returns the ownership and identity of the file.
+	Ident() (Ident, error)
 
 	// Read reads up to len(b) bytes from the File. It returns the number of bytes
 	// read and an error, if any. EOF is signaled by a zero count with err set to
@@ -110,4 +144,253 @@ type System interface {
 	// IsNotExist returns whether the error is known to report that a file does
 	// not exist.
 	IsNotExist(err error) bool
+
+	// Create creates the named file with mode 0666 (before umask), truncating
+	// it if it already exists.
+	Create(name string) (File, error)
+
+	// OpenFile is the generalized open call. It opens the named file with the
+	// specified flag (os.O_RDONLY etc.) and perm (0666 etc.) if applicable.
+	OpenFile(name string, flag int, perm os.FileMode) (File, error)
+
+	// Mkdir creates a new directory with the specified name and permission
+	// bits.
+	Mkdir(name string, perm os.FileMode) error
+
+	// MkdirAll creates a directory named path, along with any necessary
+	// parents. If path is already a directory, MkdirAll does nothing and
+	// returns nil.
+	MkdirAll(path string, perm os.FileMode) error
+
+	// Remove removes the named file or empty directory.
+	Remove(name string) error
+
+	// RemoveAll removes path and any children it contains. If the path does
+	// not exist, RemoveAll returns nil.
+	RemoveAll(path string) error
+
+	// Rename renames (moves) oldname to newname.
+	Rename(oldname, newname string) error
+}
+
+// The following interfaces describe optional capabilities of a System. A
+// System implements the ones it can support efficiently, and the functions in
+// fsutil use them when available and fall back to emulating them using Open
+// otherwise. Wrappers like limitfs implement all of them and forward to the
+// wrapped System via fsutil.
+
+// A StatSystem is a System that can describe a named file without opening it.
+type StatSystem interface {
+	System
+
+	// Stat returns the FileInfo structure describing the named file.
+	Stat(name string) (os.FileInfo, error)
+}
+
+// A LstatSystem is a System with symbolic links that can describe a link
+// itself rather than the file it refers to.
+type LstatSystem interface {
+	System
+
+	// Lstat returns the FileInfo structure describing the named file. If the
+	// file is a symbolic link, the returned FileInfo describes the symbolic
+	// link.
+	Lstat(name string) (os.FileInfo, error)
+}
+
+// A ReadDirSystem is a System that can list a directory without opening it.
+type ReadDirSystem interface {
+	System
+
+	// ReadDir returns the FileInfos for the files in the named directory,
+	// sorted by name.
+	ReadDir(name string) ([]os.FileInfo, error)
+}
+
+// A ReadFileSystem is a System that can efficiently read an entire file.
+type ReadFileSystem interface {
+	System
+
+	// ReadFile returns the contents of the named file. The caller is free to
+	// modify the returned slice.
+	ReadFile(name string) ([]byte, error)
+}
+
+// A WriteFileSystem is a System that can efficiently write an entire file.
+type WriteFileSystem interface {
+	System
+
+	// WriteFile writes data to the named file, creating it with perm if
+	// necessary and truncating it otherwise.
+	WriteFile(name string, data []byte, perm os.FileMode) error
+}
+
+// A SymlinkSystem is a System that supports symbolic links.
+type SymlinkSystem interface {
+	LstatSystem
+
+	// Symlink creates newname as a symbolic link to oldname.
+	Symlink(oldname, newname string) error
+
+	// Readlink returns the destination of the named symbolic link.
+	Readlink(name string) (string, error)
+}
+
+// A ChmodSystem is a System that can change the mode of a named file.
+type ChmodSystem interface {
+	System
+
+	// Chmod changes the mode of the named file to mode.
+	Chmod(name string, mode os.FileMode) error
+}
+
+// A ChownSystem is a System that can change the owner of a named file.
+type ChownSystem interface {
+	System
+
+	// Chown changes the numeric uid and gid of the named file.
+	Chown(name string, uid, gid int) error
+}
+
+// A ChtimesSystem is a System that can change the access and modification
+// times of a named file.
+type ChtimesSystem interface {
+	System
+
+	// Chtimes changes the access and modification times of the named file.
+	Chtimes(name string, atime time.Time, mtime time.Time) error
+}
+
+// Ident holds the ownership and identity of a file. Two files are the same if
+// they have the same Device and a non zero Inode.
+type Ident struct {
+	UID    int    // owner user id
+	GID    int    // owner group id
+	Inode  uint64 // file serial number, unique within the Device
+	Device uint64 // device containing the file
+	Nlink  uint64 // number of hard links
+}
+
+// An IdentSystem is a System that can report the ownership and identity of a
+// named file without opening it.
+type IdentSystem interface {
+	System
+
+	// Ident returns the ownership and identity of the named file.
+	Ident(name string) (Ident, error)
+}
+
+// Times holds the timestamps of a file. Times that are not known are zero.
+type Times struct {
+	Atime time.Time // last access
+	Mtime time.Time // last modification of the contents
+	Ctime time.Time // last change to the contents or metadata
+	Btime time.Time // creation, also known as birth time
+}
+
+// A TimesFileInfo is a FileInfo that also reports all the timestamps of the
+// file.
+type TimesFileInfo interface {
+	os.FileInfo
+
+	// Times returns the timestamps of the file.
+	Times() Times
+}
+
+// A TimesSystem is a System that can report all the timestamps of a named
+// file.
+type TimesSystem interface {
+	System
+
+	// Times returns the timestamps of the named file.
+	Times(name string) (Times, error)
+}
+
+// A TimesFile is a File that can report all the timestamps of the open file,
+// without resolving its name again.
+type TimesFile interface {
+	File
+
+	// Times returns the timestamps of the file.
+	Times() (Times, error)
+}
+
+// A Clock provides the current time to Systems that set the timestamps of
+// files themselves, like memfs, so tests can control them. See
+// fstest.FakeClock.
+type Clock interface {
+	// Now returns the current time.
+	Now() time.Time
+}
+
+// SystemClock is the Clock using the current time of the operating system.
+var SystemClock Clock = systemClock{}
+
+type systemClock struct{}
+
+func (systemClock) Now() time.Time { return time.Now() }
+
+// A TruncateSystem is a System that can change the size of a named file.
+type TruncateSystem interface {
+	System
+
+	// Truncate changes the size of the named file.
+	Truncate(name string, size int64) error
+}
+
+// An XattrSystem is a System that supports extended attributes on named
+// files.
+type XattrSystem interface {
+	System
+
+	// Getxattr returns the value of the extended attribute on the named file.
+	Getxattr(name, attr string) ([]byte, error)
+
+	// Setxattr sets the value of the extended attribute on the named file.
+	Setxattr(name, attr string, data []byte) error
+
+	// Listxattr returns the names of the extended attributes on the named
+	// file.
+	Listxattr(name string) ([]string, error)
+
+	// Removexattr removes the extended attribute from the named file.
+	Removexattr(name, attr string) error
+}
+
+// An XattrFile is a File that supports extended attributes.
+type XattrFile interface {
+	File
+
+	// Getxattr returns the value of the extended attribute.
+	Getxattr(attr string) ([]byte, error)
+
+	// Setxattr sets the value of the extended attribute.
+	Setxattr(attr string, data []byte) error
+
+	// Listxattr returns the names of the extended attributes.
+	Listxattr() ([]string, error)
+
+	// Removexattr removes the extended attribute.
+	Removexattr(attr string) error
+}
+
+// A LockFile is a File that supports advisory locks. Locks are held by the
+// File, and are released by Unlock or Close.
+type LockFile interface {
+	File
+
+	// Lock acquires an exclusive lock on the file, blocking until it is
+	// available.
+	Lock() error
+
+	// RLock acquires a shared lock on the file, blocking until it is
+	// available. Calling RLock while holding an exclusive lock converts it.
+	RLock() error
+
+	// TryLock is like Lock, but returns false instead of blocking if the lock
+	// is held by another File.
+	TryLock() (bool, error)
+
+	// Unlock releases the lock held by the File, if any.
+	Unlock() error
 }
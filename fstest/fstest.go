@@ -0,0 +1,758 @@
+// Package fstest provides a conformance test suite for fs.System
+// implementations.
+//
+// It is to fs.System what testing/fstest is to io/fs.FS. Implementations
+// provide a Config with a factory that creates a System populated with some
+// files, and TestSystem runs a battery of behavioral tests against it:
+//
+//	func TestConformance(t *testing.T) {
+//		fstest.TestSystem(t, fstest.Config{
+//			New: func(t *testing.T, files map[string]string) (fs.System, string) {
+//				return newMySystem(files), "."
+//			},
+//		})
+//	}
+package fstest
+
+import (
+	"errors"
+	"io"
+	"io/ioutil"
+	"os"
+	"path"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/daaku/go.fs"
+	"github.com/daaku/go.fs/fsutil"
+)
+
+// Files is the tree every System is populated with. Keys are clean slash
+// separated names, and directories are implied by the names.
+var Files = map[string]string{
+	"foo.txt":       "foo",
+	"d/bar.txt":     "bar",
+	"d/baz.txt":     "0123456789",
+	"d/e/qux.txt":   "qux",
+	"d/e/empty.txt": "",
+}
+
+// Config describes the System under test.
+type Config struct {
+	// New returns a new System populated with the given files, along with the
+	// name of the directory in the System where the files can be found. It is
+	// called once per test, so tests may modify the System. Use t.Cleanup to
+	// release any resources.
+	New func(t *testing.T, files map[string]string) (fs.System, string)
+
+	// ReadOnly Systems are expected to fail all write operations with an error
+	// matching fs.ErrReadOnly.
+	ReadOnly bool
+
+	// Empty Systems can't hold any files, so only the not-exist and write
+	// behavior is checked. New is called with no files.
+	Empty bool
+}
+
+// TestSystem runs the conformance tests against the System described by the
+// Config. Each test is run as a parallel subtest of t.
+func TestSystem(t *testing.T, c Config) {
+	var tests []test
+	if c.Empty {
+		tests = append(tests, test{"NotExist", testNotExist})
+	} else {
+		tests = append(tests, readTests...)
+		if !c.ReadOnly {
+			tests = append(tests, writeTests...)
+		}
+	}
+	if c.ReadOnly {
+		tests = append(tests, test{"ReadOnly", testReadOnly})
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			files := Files
+			if c.Empty {
+				files = nil
+			}
+			s, root := c.New(t, files)
+			tt.fn(t, env{System: s, root: root})
+		})
+	}
+}
+
+// Populate creates the files in the writable System under root, creating
+// directories as necessary.
+func Populate(s fs.System, root string, files map[string]string) error {
+	for name, data := range files {
+		name = path.Join(root, name)
+		if err := s.MkdirAll(path.Dir(name), 0755); err != nil {
+			return err
+		}
+		if err := fsutil.WriteFile(s, name, []byte(data), 0644); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+type test struct {
+	name string
+	fn   func(t *testing.T, e env)
+}
+
+var readTests = []test{
+	{"Read", testRead},
+	{"NotExist", testNotExist},
+	{"Seek", testSeek},
+	{"ReadAt", testReadAt},
+	{"Readdir", testReaddir},
+	{"ReaddirPaging", testReaddirPaging},
+	{"ReadDir", testReadDir},
+	{"Stat", testStat},
+	{"PathCleaning", testPathCleaning},
+	{"ReadDirectory", testReadDirectory},
+	{"Closed", testClosed},
+	{"Handles", testHandles},
+	{"Lock", testLock},
+	{"Walk", testWalk},
+}
+
+var writeTests = []test{
+	{"Write", testWrite},
+	{"Create", testCreate},
+	{"CreateTruncates", testCreateTruncates},
+	{"Append", testAppend},
+	{"Exclusive", testExclusive},
+	{"CreateMissingParent", testCreateMissingParent},
+	{"Mkdir", testMkdir},
+	{"Remove", testRemove},
+	{"RemoveAll", testRemoveAll},
+	{"Rename", testRename},
+	{"SharedWrites", testSharedWrites},
+}
+
+// The System under test along with the root where the files are.
+type env struct {
+	fs.System
+	root string
+}
+
+// Returns the name in the System for the given clean relative name. The name
+// is not cleaned, allowing for testing unclean names.
+func (e env) name(name string) string {
+	if e.root == "." {
+		return name
+	}
+	return strings.TrimSuffix(e.root, "/") + "/" + name
+}
+
+func (e env) open(t *testing.T, name string) fs.File {
+	t.Helper()
+	f, err := e.Open(e.name(name))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { f.Close() })
+	return f
+}
+
+func (e env) read(t *testing.T, name string) string {
+	t.Helper()
+	f, err := e.Open(e.name(name))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	b, err := ioutil.ReadAll(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(b)
+}
+
+func (e env) write(t *testing.T, name string, flag int, data string) {
+	t.Helper()
+	f, err := e.OpenFile(e.name(name), flag, 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := f.Write([]byte(data)); err != nil {
+		t.Fatal(err)
+	}
+	if err := f.Close(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func (e env) assertNotExist(t *testing.T, err error) {
+	t.Helper()
+	if !errors.Is(err, fs.ErrNotExist) || !e.IsNotExist(err) {
+		t.Fatalf("was expecting not exist error, got %v", err)
+	}
+}
+
+// Returns the sorted names from the expected files in the directory.
+func expectedNames(dir string) []string {
+	seen := make(map[string]bool)
+	prefix := dir + "/"
+	if dir == "." {
+		prefix = ""
+	}
+	for name := range Files {
+		if !strings.HasPrefix(name, prefix) {
+			continue
+		}
+		rest := strings.TrimPrefix(name, prefix)
+		if ix := strings.Index(rest, "/"); ix != -1 {
+			rest = rest[:ix]
+		}
+		seen[rest] = true
+	}
+	var names []string
+	for name := range seen {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func testRead(t *testing.T, e env) {
+	for name, data := range Files {
+		if actual := e.read(t, name); actual != data {
+			t.Fatalf("%s: was expecting %q, got %q", name, data, actual)
+		}
+	}
+	f := e.open(t, "foo.txt")
+	b := make([]byte, 2)
+	if n, err := f.Read(b); n != 2 || err != nil {
+		t.Fatalf("was expecting 2 bytes, got %d and %v", n, err)
+	}
+	if n, err := f.Read(b); n != 1 || (err != nil && err != io.EOF) {
+		t.Fatalf("was expecting 1 byte, got %d and %v", n, err)
+	}
+	if n, err := f.Read(b); n != 0 || err != io.EOF {
+		t.Fatalf("was expecting EOF, got %d and %v", n, err)
+	}
+}
+
+func testNotExist(t *testing.T, e env) {
+	_, err := e.Open(e.name("missing"))
+	e.assertNotExist(t, err)
+	_, err = e.Open(e.name("d/missing"))
+	e.assertNotExist(t, err)
+	_, err = e.Open(e.name("missing/foo.txt"))
+	e.assertNotExist(t, err)
+	_, err = fsutil.Stat(e, e.name("missing"))
+	e.assertNotExist(t, err)
+	_, err = fsutil.ReadFile(e, e.name("missing"))
+	e.assertNotExist(t, err)
+	var pe *fs.PathError
+	if _, err := e.Open(e.name("missing")); !errors.As(err, &pe) {
+		t.Fatalf("was expecting PathError, got %T", err)
+	}
+}
+
+func testSeek(t *testing.T, e env) {
+	f := e.open(t, "d/baz.txt")
+	seek := func(offset int64, whence int, expected int64, rest string) {
+		t.Helper()
+		ret, err := f.Seek(offset, whence)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if ret != expected {
+			t.Fatalf("was expecting offset %d, got %d", expected, ret)
+		}
+		b := make([]byte, len(rest))
+		if len(rest) > 0 {
+			if _, err := io.ReadFull(f, b); err != nil {
+				t.Fatal(err)
+			}
+		}
+		if string(b) != rest {
+			t.Fatalf("was expecting %q, got %q", rest, b)
+		}
+	}
+	seek(3, io.SeekStart, 3, "34")
+	seek(1, io.SeekCurrent, 6, "678")
+	seek(-4, io.SeekCurrent, 5, "5")
+	seek(0, io.SeekEnd, 10, "")
+	seek(-2, io.SeekEnd, 8, "89")
+	seek(0, io.SeekStart, 0, "0123456789")
+	if _, err := f.Seek(-1, io.SeekStart); err == nil {
+		t.Fatal("was expecting an error seeking to a negative offset")
+	}
+}
+
+func testReadAt(t *testing.T, e env) {
+	f := e.open(t, "d/baz.txt")
+	b := make([]byte, 3)
+	if n, err := f.ReadAt(b, 4); n != 3 || err != nil {
+		t.Fatalf("was expecting 3 bytes, got %d and %v", n, err)
+	}
+	if string(b) != "456" {
+		t.Fatalf("was expecting 456, got %s", b)
+	}
+	if n, err := f.ReadAt(b, 0); n != 3 || err != nil {
+		t.Fatalf("was expecting 3 bytes, got %d and %v", n, err)
+	}
+	if string(b) != "012" {
+		t.Fatalf("was expecting 012, got %s", b)
+	}
+	if n, err := f.ReadAt(b, 8); n != 2 || err != io.EOF {
+		t.Fatalf("was expecting 2 bytes and EOF, got %d and %v", n, err)
+	}
+
+	// the offset is not used or changed by ReadAt
+	if actual, err := ioutil.ReadAll(f); err != nil || string(actual) != "0123456789" {
+		t.Fatalf("was expecting 0123456789, got %q and %v", actual, err)
+	}
+}
+
+func testReaddir(t *testing.T, e env) {
+	for _, dir := range []string{".", "d", "d/e"} {
+		f := e.open(t, dir)
+		infos, err := f.Readdir(-1)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var names []string
+		for _, fi := range infos {
+			names = append(names, fi.Name())
+		}
+		sort.Strings(names)
+		if expected := expectedNames(dir); !reflect.DeepEqual(names, expected) {
+			t.Fatalf("%s: was expecting %v, got %v", dir, expected, names)
+		}
+
+		f = e.open(t, dir)
+		names, err = f.Readdirnames(-1)
+		if err != nil {
+			t.Fatal(err)
+		}
+		sort.Strings(names)
+		if expected := expectedNames(dir); !reflect.DeepEqual(names, expected) {
+			t.Fatalf("%s: was expecting %v, got %v", dir, expected, names)
+		}
+	}
+}
+
+func testReaddirPaging(t *testing.T, e env) {
+	f := e.open(t, "d")
+	var names []string
+	for i := 0; ; i++ {
+		if i > 10 {
+			t.Fatal("was expecting EOF")
+		}
+		infos, err := f.Readdir(1)
+		if len(infos) > 1 {
+			t.Fatalf("was expecting at most 1 entry, got %d", len(infos))
+		}
+		for _, fi := range infos {
+			names = append(names, fi.Name())
+		}
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(infos) == 0 {
+			t.Fatal("was expecting an entry or EOF")
+		}
+	}
+	sort.Strings(names)
+	if expected := expectedNames("d"); !reflect.DeepEqual(names, expected) {
+		t.Fatalf("was expecting %v, got %v", expected, names)
+	}
+}
+
+func testReadDir(t *testing.T, e env) {
+	infos, err := fsutil.ReadDir(e, e.name("d"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var names []string
+	for _, fi := range infos {
+		names = append(names, fi.Name())
+	}
+	if expected := expectedNames("d"); !reflect.DeepEqual(names, expected) {
+		t.Fatalf("was expecting sorted %v, got %v", expected, names)
+	}
+	if _, err := fsutil.ReadDir(e, e.name("foo.txt")); err == nil {
+		t.Fatal("was expecting an error reading a file as a directory")
+	}
+}
+
+func testStat(t *testing.T, e env) {
+	for name, data := range Files {
+		fi, err := fsutil.Stat(e, e.name(name))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if fi.IsDir() || !fi.Mode().IsRegular() {
+			t.Fatalf("%s: was expecting a regular file, got %v", name, fi.Mode())
+		}
+		if fi.Size() != int64(len(data)) {
+			t.Fatalf("%s: was expecting size %d, got %d", name, len(data), fi.Size())
+		}
+		if base := name[strings.LastIndex(name, "/")+1:]; fi.Name() != base {
+			t.Fatalf("%s: was expecting name %s, got %s", name, base, fi.Name())
+		}
+		fi, err = e.open(t, name).Stat()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if fi.Size() != int64(len(data)) {
+			t.Fatalf("%s: was expecting size %d, got %d", name, len(data), fi.Size())
+		}
+	}
+	for _, dir := range []string{".", "d", "d/e"} {
+		fi, err := fsutil.Stat(e, e.name(dir))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !fi.IsDir() || !fi.Mode().IsDir() {
+			t.Fatalf("%s: was expecting a directory, got %v", dir, fi.Mode())
+		}
+	}
+}
+
+func testPathCleaning(t *testing.T, e env) {
+	names := []string{
+		"d/bar.txt",
+		"./d/bar.txt",
+		"d//bar.txt",
+		"d/./bar.txt",
+		"d/e/../bar.txt",
+	}
+	for _, name := range names {
+		if actual := e.read(t, name); actual != "bar" {
+			t.Fatalf("%s: was expecting bar, got %q", name, actual)
+		}
+	}
+}
+
+func testReadDirectory(t *testing.T, e env) {
+	f := e.open(t, "d")
+	if _, err := f.Read(make([]byte, 1)); err == nil || err == io.EOF {
+		t.Fatalf("was expecting an error reading a directory, got %v", err)
+	}
+	f = e.open(t, "foo.txt")
+	if _, err := f.Readdir(-1); err == nil {
+		t.Fatal("was expecting an error listing a file")
+	}
+}
+
+func testClosed(t *testing.T, e env) {
+	f, err := e.Open(e.name("foo.txt"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := f.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := f.Read(make([]byte, 1)); !errors.Is(err, fs.ErrClosed) {
+		t.Fatalf("was expecting ErrClosed, got %v", err)
+	}
+}
+
+func testHandles(t *testing.T, e env) {
+	f1 := e.open(t, "d/baz.txt")
+	f2 := e.open(t, "d/baz.txt")
+	b := make([]byte, 3)
+	if _, err := io.ReadFull(f1, b); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := io.ReadFull(f2, b[:1]); err != nil {
+		t.Fatal(err)
+	}
+	if b[0] != '0' {
+		t.Fatalf("was expecting each handle to have its own offset, got %q", b[0])
+	}
+	if err := f1.Close(); err != nil {
+		t.Fatal(err)
+	}
+	rest, err := ioutil.ReadAll(f2)
+	if err != nil {
+		t.Fatalf("was expecting the other handle to remain open, got %v", err)
+	}
+	if string(rest) != "123456789" {
+		t.Fatalf("was expecting 123456789, got %q", rest)
+	}
+}
+
+func testLock(t *testing.T, e env) {
+	f1 := e.open(t, "foo.txt")
+	f2 := e.open(t, "foo.txt")
+	if err := fsutil.Lock(f1); err != nil {
+		if errors.Is(err, fs.ErrNotSupported) {
+			t.Skip(err)
+		}
+		t.Fatal(err)
+	}
+	if locked, err := fsutil.TryLock(f2); err != nil || locked {
+		t.Fatalf("was expecting the lock to be held, got %v and %v", locked, err)
+	}
+	if err := fsutil.Unlock(f1); err != nil {
+		t.Fatal(err)
+	}
+	if locked, err := fsutil.TryLock(f2); err != nil || !locked {
+		t.Fatalf("was expecting to acquire the lock, got %v and %v", locked, err)
+	}
+}
+
+func testWalk(t *testing.T, e env) {
+	var names []string
+	err := fsutil.Walk(e, e.root, func(name string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if !info.IsDir() {
+			name = strings.TrimPrefix(strings.TrimPrefix(name, e.root), "/")
+			names = append(names, name)
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var expected []string
+	for name := range Files {
+		expected = append(expected, name)
+	}
+	sort.Strings(expected)
+	sort.Strings(names)
+	if !reflect.DeepEqual(names, expected) {
+		t.Fatalf("was expecting %v, got %v", expected, names)
+	}
+}
+
+func testWrite(t *testing.T, e env) {
+	f, err := e.OpenFile(e.name("d/baz.txt"), os.O_RDWR, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	if _, err := f.Write([]byte("ab")); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := f.WriteAt([]byte("cd"), 4); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := f.Seek(0, io.SeekEnd); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := f.WriteString("ef"); err != nil {
+		t.Fatal(err)
+	}
+	if err := f.Sync(); err != nil {
+		t.Fatal(err)
+	}
+	if err := f.Truncate(11); err != nil {
+		t.Fatal(err)
+	}
+	if actual := e.read(t, "d/baz.txt"); actual != "ab23cd6789e" {
+		t.Fatalf("was expecting ab23cd6789e, got %q", actual)
+	}
+}
+
+func testCreate(t *testing.T, e env) {
+	f, err := e.Create(e.name("new.txt"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := f.WriteString("new"); err != nil {
+		t.Fatal(err)
+	}
+	if err := f.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if actual := e.read(t, "new.txt"); actual != "new" {
+		t.Fatalf("was expecting new, got %q", actual)
+	}
+	fi, err := fsutil.Stat(e, e.name("new.txt"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fi.Size() != 3 {
+		t.Fatalf("was expecting size 3, got %d", fi.Size())
+	}
+	names, err := e.open(t, ".").Readdirnames(-1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sort.Strings(names)
+	if ix := sort.SearchStrings(names, "new.txt"); ix == len(names) || names[ix] != "new.txt" {
+		t.Fatalf("was expecting new.txt in the listing, got %v", names)
+	}
+}
+
+func testCreateTruncates(t *testing.T, e env) {
+	e.write(t, "d/baz.txt", os.O_RDWR|os.O_CREATE|os.O_TRUNC, "new")
+	if actual := e.read(t, "d/baz.txt"); actual != "new" {
+		t.Fatalf("was expecting new, got %q", actual)
+	}
+}
+
+func testAppend(t *testing.T, e env) {
+	e.write(t, "foo.txt", os.O_WRONLY|os.O_APPEND, "bar")
+	if actual := e.read(t, "foo.txt"); actual != "foobar" {
+		t.Fatalf("was expecting foobar, got %q", actual)
+	}
+}
+
+func testExclusive(t *testing.T, e env) {
+	_, err := e.OpenFile(e.name("foo.txt"), os.O_RDWR|os.O_CREATE|os.O_EXCL, 0644)
+	if !errors.Is(err, fs.ErrExist) {
+		t.Fatalf("was expecting ErrExist, got %v", err)
+	}
+	e.write(t, "excl.txt", os.O_RDWR|os.O_CREATE|os.O_EXCL, "excl")
+	if actual := e.read(t, "excl.txt"); actual != "excl" {
+		t.Fatalf("was expecting excl, got %q", actual)
+	}
+}
+
+func testCreateMissingParent(t *testing.T, e env) {
+	_, err := e.Create(e.name("missing/new.txt"))
+	e.assertNotExist(t, err)
+}
+
+func testMkdir(t *testing.T, e env) {
+	if err := e.Mkdir(e.name("new"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := e.Mkdir(e.name("new"), 0755); !errors.Is(err, fs.ErrExist) {
+		t.Fatalf("was expecting ErrExist, got %v", err)
+	}
+	if err := e.Mkdir(e.name("missing/new"), 0755); !errors.Is(err, fs.ErrNotExist) {
+		t.Fatalf("was expecting ErrNotExist, got %v", err)
+	}
+	if err := e.MkdirAll(e.name("new/a/b"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := e.MkdirAll(e.name("new/a/b"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	e.write(t, "new/a/b/c.txt", os.O_RDWR|os.O_CREATE, "c")
+	if actual := e.read(t, "new/a/b/c.txt"); actual != "c" {
+		t.Fatalf("was expecting c, got %q", actual)
+	}
+	fi, err := fsutil.Stat(e, e.name("new/a"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !fi.IsDir() {
+		t.Fatal("was expecting a directory")
+	}
+}
+
+func testRemove(t *testing.T, e env) {
+	if err := e.Remove(e.name("foo.txt")); err != nil {
+		t.Fatal(err)
+	}
+	_, err := e.Open(e.name("foo.txt"))
+	e.assertNotExist(t, err)
+	e.assertNotExist(t, e.Remove(e.name("foo.txt")))
+	if err := e.Remove(e.name("d")); !errors.Is(err, fs.ErrNotEmpty) {
+		t.Fatalf("was expecting ErrNotEmpty, got %v", err)
+	}
+	for _, name := range expectedNames("d/e") {
+		if err := e.Remove(e.name("d/e/" + name)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := e.Remove(e.name("d/e")); err != nil {
+		t.Fatal(err)
+	}
+	names, err := e.open(t, "d").Readdirnames(-1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sort.Strings(names)
+	if !reflect.DeepEqual(names, []string{"bar.txt", "baz.txt"}) {
+		t.Fatalf("did not find expected names, got %v", names)
+	}
+}
+
+func testRemoveAll(t *testing.T, e env) {
+	if err := e.RemoveAll(e.name("d")); err != nil {
+		t.Fatal(err)
+	}
+	_, err := e.Open(e.name("d/e/qux.txt"))
+	e.assertNotExist(t, err)
+	_, err = e.Open(e.name("d"))
+	e.assertNotExist(t, err)
+	if err := e.RemoveAll(e.name("d")); err != nil {
+		t.Fatalf("was expecting no error removing a missing path, got %v", err)
+	}
+	if actual := e.read(t, "foo.txt"); actual != "foo" {
+		t.Fatalf("was expecting foo, got %q", actual)
+	}
+}
+
+func testRename(t *testing.T, e env) {
+	if err := e.Rename(e.name("foo.txt"), e.name("d/moved.txt")); err != nil {
+		t.Fatal(err)
+	}
+	_, err := e.Open(e.name("foo.txt"))
+	e.assertNotExist(t, err)
+	if actual := e.read(t, "d/moved.txt"); actual != "foo" {
+		t.Fatalf("was expecting foo, got %q", actual)
+	}
+	if err := e.Rename(e.name("d/e"), e.name("e")); err != nil {
+		t.Fatal(err)
+	}
+	if actual := e.read(t, "e/qux.txt"); actual != "qux" {
+		t.Fatalf("was expecting qux, got %q", actual)
+	}
+	err = e.Rename(e.name("missing"), e.name("other"))
+	if !errors.Is(err, fs.ErrNotExist) {
+		t.Fatalf("was expecting ErrNotExist, got %v", err)
+	}
+}
+
+func testSharedWrites(t *testing.T, e env) {
+	r := e.open(t, "foo.txt")
+	w, err := e.OpenFile(e.name("foo.txt"), os.O_WRONLY, 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer w.Close()
+	if _, err := w.WriteString("bar"); err != nil {
+		t.Fatal(err)
+	}
+	b, err := ioutil.ReadAll(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(b) != "bar" {
+		t.Fatalf("was expecting writes to be visible to other handles, got %q", b)
+	}
+}
+
+func testReadOnly(t *testing.T, e env) {
+	assertReadOnly := func(err error) {
+		t.Helper()
+		if !errors.Is(err, fs.ErrReadOnly) {
+			t.Fatalf("was expecting ErrReadOnly, got %v", err)
+		}
+	}
+	_, err := e.Create(e.name("new.txt"))
+	assertReadOnly(err)
+	_, err = e.OpenFile(e.name("foo.txt"), os.O_WRONLY, 0)
+	assertReadOnly(err)
+	_, err = e.OpenFile(e.name("foo.txt"), os.O_RDWR|os.O_APPEND, 0)
+	assertReadOnly(err)
+	assertReadOnly(e.Mkdir(e.name("new"), 0755))
+	assertReadOnly(e.MkdirAll(e.name("new/a"), 0755))
+	assertReadOnly(e.Remove(e.name("foo.txt")))
+	assertReadOnly(e.RemoveAll(e.name("d")))
+	assertReadOnly(e.Rename(e.name("foo.txt"), e.name("bar.txt")))
+	assertReadOnly(fsutil.WriteFile(e, e.name("foo.txt"), nil, 0644))
+}
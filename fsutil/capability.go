@@ -0,0 +1,380 @@
+package fsutil
+
+import (
+	"io"
+	"io/ioutil"
+	"os"
+	"sort"
+	"time"
+
+	"github.com/daaku/go.fs"
+)
+
+// Stat returns the FileInfo for the named file. It uses the Stat method if the
+// System is a fs.StatSystem, otherwise it opens the file and uses File.Stat.
+func Stat(s fs.System, name string) (os.FileInfo, error) {
+	if ss, ok := s.(fs.StatSystem); ok {
+		return ss.Stat(name)
+	}
+	f, err := s.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return f.Stat()
+}
+
+// Lstat returns the FileInfo for the named file without following symbolic
+// links. It uses the Lstat method if the System is a fs.LstatSystem, otherwise
+// the System has no symbolic links and it's the same as Stat.
+func Lstat(s fs.System, name string) (os.FileInfo, error) {
+	if ls, ok := s.(fs.LstatSystem); ok {
+		return ls.Lstat(name)
+	}
+	return Stat(s, name)
+}
+
+// ReadDir returns the FileInfos for the files in the named directory, sorted
+// by name. It uses the ReadDir method if the System is a fs.ReadDirSystem,
+// otherwise it opens the directory and uses File.Readdir.
+func ReadDir(s fs.System, name string) ([]os.FileInfo, error) {
+	if rs, ok := s.(fs.ReadDirSystem); ok {
+		return rs.ReadDir(name)
+	}
+	f, err := s.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	infos, err := f.Readdir(-1)
+	if err != nil && err != io.EOF {
+		return nil, err
+	}
+	SortByName(infos)
+	return infos, nil
+}
+
+// ReadFile returns the contents of the named file. It uses the ReadFile method
+// if the System is a fs.ReadFileSystem, otherwise it opens the file and reads
+// it until EOF.
+func ReadFile(s fs.System, name string) ([]byte, error) {
+	if rs, ok := s.(fs.ReadFileSystem); ok {
+		return rs.ReadFile(name)
+	}
+	f, err := s.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return ioutil.ReadAll(f)
+}
+
+// WriteFile writes data to the named file, creating it with perm if necessary
+// and truncating it otherwise. It uses the WriteFile method if the System is a
+// fs.WriteFileSystem, otherwise it opens the file for writing and writes the
+// data.
+func WriteFile(s fs.System, name string, data []byte, perm os.FileMode) error {
+	if ws, ok := s.(fs.WriteFileSystem); ok {
+		return ws.WriteFile(name, data, perm)
+	}
+	f, err := s.OpenFile(name, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, perm)
+	if err != nil {
+		return err
+	}
+	_, err = f.Write(data)
+	if errC := f.Close(); err == nil {
+		err = errC
+	}
+	return err
+}
+
+// Exists reports whether the named file exists. An error is only returned if
+// the existence could not be determined.
+func Exists(s fs.System, name string) (bool, error) {
+	_, err := Stat(s, name)
+	if err == nil {
+		return true, nil
+	}
+	if s.IsNotExist(err) {
+		return false, nil
+	}
+	return false, err
+}
+
+// Symlink creates newname as a symbolic link to oldname. Systems that are not
+// a fs.SymlinkSystem do not support symbolic links.
+func Symlink(s fs.System, oldname, newname string) error {
+	if ls, ok := s.(fs.SymlinkSystem); ok {
+		return ls.Symlink(oldname, newname)
+	}
+	return &fs.LinkError{
+		Op:  "symlink",
+		Old: oldname,
+		New: newname,
+		Err: fs.ErrNotSupported,
+	}
+}
+
+// Readlink returns the destination of the named symbolic link. Systems that
+// are not a fs.SymlinkSystem do not support symbolic links.
+func Readlink(s fs.System, name string) (string, error) {
+	if ls, ok := s.(fs.SymlinkSystem); ok {
+		return ls.Readlink(name)
+	}
+	return "", &fs.PathError{Op: "readlink", Path: name, Err: fs.ErrNotSupported}
+}
+
+// Chmod changes the mode of the named file. It uses the Chmod method if the
+// System is a fs.ChmodSystem, otherwise it opens the file and uses File.Chmod.
+func Chmod(s fs.System, name string, mode os.FileMode) error {
+	if cs, ok := s.(fs.ChmodSystem); ok {
+		return cs.Chmod(name, mode)
+	}
+	f, err := s.Open(name)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	return f.Chmod(mode)
+}
+
+// Chown changes the numeric uid and gid of the named file. It uses the Chown
+// method if the System is a fs.ChownSystem, otherwise it opens the file and
+// uses File.Chown.
+func Chown(s fs.System, name string, uid, gid int) error {
+	if cs, ok := s.(fs.ChownSystem); ok {
+		return cs.Chown(name, uid, gid)
+	}
+	f, err := s.Open(name)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	return f.Chown(uid, gid)
+}
+
+// Chtimes changes the access and modification times of the named file.
+// Systems that are not a fs.ChtimesSystem do not support changing times.
+func Chtimes(s fs.System, name string, atime time.Time, mtime time.Time) error {
+	if cs, ok := s.(fs.ChtimesSystem); ok {
+		return cs.Chtimes(name, atime, mtime)
+	}
+	return &fs.PathError{Op: "chtimes", Path: name, Err: fs.ErrNotSupported}
+}
+
+// Ident returns the ownership and identity of the named file, using the File
+// if the System is not a fs.IdentSystem.
+func Ident(s fs.System, name string) (fs.Ident, error) {
+	if is, ok := s.(fs.IdentSystem); ok {
+		return is.Ident(name)
+	}
+	f, err := s.Open(name)
+	if err != nil {
+		return fs.Ident{}, err
+	}
+	defer f.Close()
+	return f.Ident()
+}
+
+// Times returns the timestamps of the named file. Systems that are not a
+// fs.TimesSystem report the times from Stat, see InfoTimes.
+func Times(s fs.System, name string) (fs.Times, error) {
+	if ts, ok := s.(fs.TimesSystem); ok {
+		return ts.Times(name)
+	}
+	fi, err := Stat(s, name)
+	if err != nil {
+		return fs.Times{}, err
+	}
+	return InfoTimes(fi), nil
+}
+
+// FileTimes returns the timestamps of the open File. Files that are not a
+// fs.TimesFile report the times from Stat, see InfoTimes.
+func FileTimes(f fs.File) (fs.Times, error) {
+	if tf, ok := f.(fs.TimesFile); ok {
+		return tf.Times()
+	}
+	fi, err := f.Stat()
+	if err != nil {
+		return fs.Times{}, err
+	}
+	return InfoTimes(fi), nil
+}
+
+// InfoTimes returns the timestamps from a FileInfo. Only the modification time
+// is known unless it is a fs.TimesFileInfo.
+func InfoTimes(fi os.FileInfo) fs.Times {
+	if tfi, ok := fi.(fs.TimesFileInfo); ok {
+		return tfi.Times()
+	}
+	return fs.Times{Mtime: fi.ModTime()}
+}
+
+// Truncate changes the size of the named file. It uses the Truncate method if
+// the System is a fs.TruncateSystem, otherwise it opens the file for writing
+// and uses File.Truncate.
+func Truncate(s fs.System, name string, size int64) error {
+	if ts, ok := s.(fs.TruncateSystem); ok {
+		return ts.Truncate(name, size)
+	}
+	f, err := s.OpenFile(name, os.O_WRONLY, 0)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	return f.Truncate(size)
+}
+
+// Getxattr returns the value of the extended attribute on the named file,
+// using the File if the System is not a fs.XattrSystem.
+func Getxattr(s fs.System, name, attr string) ([]byte, error) {
+	if xs, ok := s.(fs.XattrSystem); ok {
+		return xs.Getxattr(name, attr)
+	}
+	f, err := openXattr(s, "getxattr", name)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return f.Getxattr(attr)
+}
+
+// Setxattr sets the value of the extended attribute on the named file, using
+// the File if the System is not a fs.XattrSystem.
+func Setxattr(s fs.System, name, attr string, data []byte) error {
+	if xs, ok := s.(fs.XattrSystem); ok {
+		return xs.Setxattr(name, attr, data)
+	}
+	f, err := openXattr(s, "setxattr", name)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	return f.Setxattr(attr, data)
+}
+
+// Listxattr returns the names of the extended attributes on the named file,
+// using the File if the System is not a fs.XattrSystem.
+func Listxattr(s fs.System, name string) ([]string, error) {
+	if xs, ok := s.(fs.XattrSystem); ok {
+		return xs.Listxattr(name)
+	}
+	f, err := openXattr(s, "listxattr", name)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return f.Listxattr()
+}
+
+// Removexattr removes the extended attribute from the named file, using the
+// File if the System is not a fs.XattrSystem.
+func Removexattr(s fs.System, name, attr string) error {
+	if xs, ok := s.(fs.XattrSystem); ok {
+		return xs.Removexattr(name, attr)
+	}
+	f, err := openXattr(s, "removexattr", name)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	return f.Removexattr(attr)
+}
+
+// Lock acquires an exclusive advisory lock on the File, blocking until it is
+// available. It fails with fs.ErrNotSupported if the File is not a
+// fs.LockFile.
+func Lock(f fs.File) error {
+	lf, err := lockFile(f, "lock")
+	if err != nil {
+		return err
+	}
+	return lf.Lock()
+}
+
+// RLock acquires a shared advisory lock on the File, blocking until it is
+// available. It fails with fs.ErrNotSupported if the File is not a
+// fs.LockFile.
+func RLock(f fs.File) error {
+	lf, err := lockFile(f, "lock")
+	if err != nil {
+		return err
+	}
+	return lf.RLock()
+}
+
+// TryLock is like Lock, but returns false instead of blocking if the lock is
+// held by another File.
+func TryLock(f fs.File) (bool, error) {
+	lf, err := lockFile(f, "lock")
+	if err != nil {
+		return false, err
+	}
+	return lf.TryLock()
+}
+
+// Unlock releases the lock held by the File, if any. It fails with
+// fs.ErrNotSupported if the File is not a fs.LockFile.
+func Unlock(f fs.File) error {
+	lf, err := lockFile(f, "unlock")
+	if err != nil {
+		return err
+	}
+	return lf.Unlock()
+}
+
+// Ensures the File supports locks.
+func lockFile(f fs.File, op string) (fs.LockFile, error) {
+	lf, ok := f.(fs.LockFile)
+	if !ok {
+		return nil, &fs.PathError{Op: op, Path: fileName(f), Err: fs.ErrNotSupported}
+	}
+	return lf, nil
+}
+
+// Returns the name of the File for errors, falling back to the base name from
+// Stat if the File doesn't know its full name.
+func fileName(f fs.File) string {
+	if nf, ok := f.(interface{ Name() string }); ok {
+		return nf.Name()
+	}
+	if fi, err := f.Stat(); err == nil {
+		return fi.Name()
+	}
+	return ""
+}
+
+// Opens the named file, ensuring it supports extended attributes.
+func openXattr(s fs.System, op, name string) (fs.XattrFile, error) {
+	f, err := s.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	xf, ok := f.(fs.XattrFile)
+	if !ok {
+		f.Close()
+		return nil, &fs.PathError{Op: op, Path: name, Err: fs.ErrNotSupported}
+	}
+	return xf, nil
+}
+
+// Watch starts watching the named file or directory for changes. Systems that
+// are not a fs.WatchSystem do not support watching.
+func Watch(s fs.System, name string, recursive bool) (fs.Watcher, error) {
+	if ws, ok := s.(fs.WatchSystem); ok {
+		return ws.Watch(name, recursive)
+	}
+	return nil, &fs.PathError{Op: "watch", Path: name, Err: fs.ErrNotSupported}
+}
+
+type byName []os.FileInfo
+
+func (b byName) Len() int           { return len(b) }
+func (b byName) Less(i, j int) bool { return b[i].Name() < b[j].Name() }
+func (b byName) Swap(i, j int)      { b[i], b[j] = b[j], b[i] }
+
+// SortByName sorts the FileInfos by name, as expected from ReadDir.
+func SortByName(infos []os.FileInfo) {
+	sort.Sort(byName(infos))
+}
@@ -0,0 +1,157 @@
+package fsutil_test
+
+import (
+	"errors"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/daaku/go.fs"
+	"github.com/daaku/go.fs/fsutil"
+	"github.com/daaku/go.fs/memfs"
+)
+
+// Hides all the optional capabilities to force the fallbacks.
+type plain struct {
+	fs.System
+}
+
+func newPlainSystem() fs.System {
+	return plain{memfs.NewWithFiles(map[string]fs.File{
+		"d/foo": memfs.NewFile("foo", 0644, time.Now(), []byte("foo")),
+		"d/bar": memfs.NewFile("bar", 0644, time.Now(), []byte("bar")),
+	})}
+}
+
+func TestFallbacks(t *testing.T) {
+	t.Parallel()
+	s := newPlainSystem()
+	if _, ok := s.(fs.StatSystem); ok {
+		t.Fatal("was not expecting a StatSystem")
+	}
+
+	fi, err := fsutil.Stat(s, "d/foo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fi.Size() != 3 {
+		t.Fatalf("was expecting size 3, got %d", fi.Size())
+	}
+	if _, err := fsutil.Lstat(s, "d/missing"); !errors.Is(err, fs.ErrNotExist) {
+		t.Fatalf("was expecting ErrNotExist, got %v", err)
+	}
+
+	infos, err := fsutil.ReadDir(s, "d")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(infos) != 2 || infos[0].Name() != "bar" || infos[1].Name() != "foo" {
+		t.Fatalf("did not find expected sorted infos, got %v", infos)
+	}
+
+	b, err := fsutil.ReadFile(s, "d/foo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(b) != "foo" {
+		t.Fatalf("was expecting foo, got %s", b)
+	}
+
+	if err := fsutil.Chmod(s, "d/foo", 0600); err != nil {
+		t.Fatal(err)
+	}
+	if err := fsutil.Truncate(s, "d/foo", 1); err != nil {
+		t.Fatal(err)
+	}
+	fi, err = fsutil.Stat(s, "d/foo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fi.Mode() != 0600 || fi.Size() != 1 {
+		t.Fatalf("was expecting mode 0600 and size 1, got %v and %d", fi.Mode(), fi.Size())
+	}
+
+	if err := fsutil.Setxattr(s, "d/foo", "user.a", []byte("1")); err != nil {
+		t.Fatal(err)
+	}
+	b, err = fsutil.Getxattr(s, "d/foo", "user.a")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(b) != "1" {
+		t.Fatalf("was expecting 1, got %s", b)
+	}
+	if err := fsutil.Removexattr(s, "d/foo", "user.a"); err != nil {
+		t.Fatal(err)
+	}
+	attrs, err := fsutil.Listxattr(s, "d/foo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(attrs) != 0 {
+		t.Fatalf("was expecting no attributes, got %v", attrs)
+	}
+}
+
+func TestWriteFileAndExists(t *testing.T) {
+	t.Parallel()
+	for _, s := range []fs.System{newPlainSystem(), memfs.NewWithFiles(nil)} {
+		if err := fsutil.WriteFile(s, "foo", []byte("foo"), 0644); err != nil {
+			t.Fatal(err)
+		}
+		if err := fsutil.WriteFile(s, "foo", []byte("b"), 0644); err != nil {
+			t.Fatal(err)
+		}
+		b, err := fsutil.ReadFile(s, "foo")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(b) != "b" {
+			t.Fatalf("was expecting b, got %s", b)
+		}
+		exists, err := fsutil.Exists(s, "foo")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !exists {
+			t.Fatal("was expecting foo to exist")
+		}
+		exists, err = fsutil.Exists(s, "missing")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if exists {
+			t.Fatal("was not expecting missing to exist")
+		}
+	}
+}
+
+func TestUnsupportedFallbacks(t *testing.T) {
+	t.Parallel()
+	s := newPlainSystem()
+	if err := fsutil.Symlink(s, "d/foo", "d/baz"); !errors.Is(err, fs.ErrNotSupported) {
+		t.Fatalf("was expecting ErrNotSupported, got %v", err)
+	}
+	if _, err := fsutil.Readlink(s, "d/foo"); !errors.Is(err, fs.ErrNotSupported) {
+		t.Fatalf("was expecting ErrNotSupported, got %v", err)
+	}
+	err := fsutil.Chtimes(s, "d/foo", time.Now(), time.Now())
+	if !errors.Is(err, fs.ErrNotSupported) {
+		t.Fatalf("was expecting ErrNotSupported, got %v", err)
+	}
+	if _, err := fsutil.Watch(s, "d", false); !errors.Is(err, fs.ErrNotSupported) {
+		t.Fatalf("was expecting ErrNotSupported, got %v", err)
+	}
+}
+
+func TestSortByName(t *testing.T) {
+	t.Parallel()
+	infos := []os.FileInfo{
+		memfs.NewFileInfo(memfs.FileInfo{Name: "b"}),
+		memfs.NewFileInfo(memfs.FileInfo{Name: "a"}),
+	}
+	fsutil.SortByName(infos)
+	if infos[0].Name() != "a" || infos[1].Name() != "b" {
+		t.Fatalf("was expecting sorted infos, got %v", infos)
+	}
+}
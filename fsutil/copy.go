@@ -0,0 +1,208 @@
+package fsutil
+
+import (
+	"io"
+	"os"
+	"path"
+	"strings"
+
+	"github.com/daaku/go.fs"
+)
+
+// OverwritePolicy controls what CopyTree does when a file already exists in
+// the destination. Existing directories are always merged into.
+type OverwritePolicy int
+
+const (
+	OverwriteError   OverwritePolicy = iota // fail with fs.ErrExist
+	OverwriteSkip                           // leave the existing file alone
+	OverwriteAlways                         // replace the existing file
+	OverwriteIfNewer                        // replace if the source mtime is newer
+)
+
+// CopyOptions configures CopyTree. The zero value copies the data only, and
+// fails if a file already exists in the destination.
+type CopyOptions struct {
+	PreserveMode  bool // copy permission bits, instead of using 0666 and 0777
+	PreserveTimes bool // copy the access and modification times
+	PreserveOwner bool // copy the numeric uid and gid
+	Overwrite     OverwritePolicy
+
+	// Filter is called with the source name of every file and directory. If
+	// it returns false, the file or the entire directory is skipped.
+	Filter func(name string, info os.FileInfo) bool
+
+	// Progress is called with the destination name after every file or
+	// directory is copied, along with the number of bytes written.
+	Progress func(name string, info os.FileInfo, written int64)
+}
+
+// CopyTree copies the file or directory at srcPath in src to dstPath in dst.
+// Directories are copied recursively, and symbolic links are recreated when
+// both Systems support them. Other special files like devices and sockets are
+// skipped.
+func CopyTree(dst fs.System, dstPath string, src fs.System, srcPath string, opts CopyOptions) error {
+	c := copier{dst: dst, src: src, opts: opts}
+	err := Walk(src, srcPath, func(name string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if opts.Filter != nil && !opts.Filter(name, info) {
+			if info.IsDir() {
+				return SkipDir
+			}
+			return nil
+		}
+		target := path.Join(dstPath, relativeTo(srcPath, name))
+		return c.copy(target, name, info)
+	})
+	if err != nil {
+		return err
+	}
+
+	// directory metadata is applied last, since adding the children changes
+	// the modification times
+	for ix := len(c.dirs) - 1; ix >= 0; ix-- {
+		d := c.dirs[ix]
+		if err := c.metadata(d.target, d.name, d.info); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Returns the name relative to the root of a walk.
+func relativeTo(root, name string) string {
+	switch {
+	case name == root:
+		return ""
+	case root == ".":
+		return name
+	}
+	return strings.TrimPrefix(strings.TrimPrefix(name, root), "/")
+}
+
+type copiedDir struct {
+	target string
+	name   string
+	info   os.FileInfo
+}
+
+type copier struct {
+	dst  fs.System
+	src  fs.System
+	opts CopyOptions
+	dirs []copiedDir
+}
+
+func (c *copier) copy(target, name string, info os.FileInfo) error {
+	existing, err := Lstat(c.dst, target)
+	if err != nil && !c.dst.IsNotExist(err) {
+		return err
+	}
+	if existing != nil {
+		replace, err := c.replace(target, info, existing)
+		if err != nil || !replace {
+			return err
+		}
+	}
+
+	var written int64
+	switch mode := info.Mode(); {
+	case mode.IsDir():
+		if existing == nil {
+			if err := c.dst.Mkdir(target, 0777); err != nil {
+				return err
+			}
+		}
+		c.dirs = append(c.dirs, copiedDir{target: target, name: name, info: info})
+	case mode&os.ModeSymlink != 0:
+		link, err := Readlink(c.src, name)
+		if err != nil {
+			return err
+		}
+		if err := Symlink(c.dst, link, target); err != nil {
+			return err
+		}
+	case mode.IsRegular():
+		if written, err = c.copyFile(target, name); err != nil {
+			return err
+		}
+		if err := c.metadata(target, name, info); err != nil {
+			return err
+		}
+	default:
+		return nil
+	}
+
+	if c.opts.Progress != nil {
+		c.opts.Progress(target, info, written)
+	}
+	return nil
+}
+
+// Applies the Overwrite policy, and removes the existing file if it should
+// be replaced. Directories are merged into.
+func (c *copier) replace(target string, info, existing os.FileInfo) (bool, error) {
+	if info.IsDir() && existing.IsDir() {
+		return true, nil
+	}
+	switch c.opts.Overwrite {
+	case OverwriteSkip:
+		return false, nil
+	case OverwriteIfNewer:
+		if !info.ModTime().After(existing.ModTime()) {
+			return false, nil
+		}
+	case OverwriteError:
+		return false, &fs.PathError{Op: "copy", Path: target, Err: fs.ErrExist}
+	}
+	if existing.IsDir() {
+		return false, &fs.PathError{Op: "copy", Path: target, Err: fs.ErrIsDir}
+	}
+	return true, c.dst.Remove(target)
+}
+
+func (c *copier) copyFile(target, name string) (int64, error) {
+	r, err := c.src.Open(name)
+	if err != nil {
+		return 0, err
+	}
+	defer r.Close()
+	w, err := c.dst.OpenFile(target, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0666)
+	if err != nil {
+		return 0, err
+	}
+	written, err := io.Copy(w, r)
+	if errC := w.Close(); err == nil {
+		err = errC
+	}
+	return written, err
+}
+
+func (c *copier) metadata(target, name string, info os.FileInfo) error {
+	if c.opts.PreserveOwner {
+		ident, err := Ident(c.src, name)
+		if err != nil {
+			return err
+		}
+		if err := Chown(c.dst, target, ident.UID, ident.GID); err != nil {
+			return err
+		}
+	}
+	if c.opts.PreserveMode {
+		if err := Chmod(c.dst, target, info.Mode()&^os.ModeType); err != nil {
+			return err
+		}
+	}
+	if c.opts.PreserveTimes {
+		times := InfoTimes(info)
+		if times.Atime.IsZero() {
+			times.Atime = times.Mtime
+		}
+		if err := Chtimes(c.dst, target, times.Atime, times.Mtime); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,177 @@
+package fsutil_test
+
+import (
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/daaku/go.fs"
+	"github.com/daaku/go.fs/fsutil"
+	"github.com/daaku/go.fs/memfs"
+	"github.com/daaku/go.fs/realfs"
+)
+
+func newCopySource(mtime time.Time) fs.System {
+	return memfs.NewWithFiles(map[string]fs.File{
+		"src/a.txt":   memfs.NewFile("a.txt", 0600, mtime, []byte("a")),
+		"src/d/b.txt": memfs.NewFile("b.txt", 0640, mtime, []byte("bb")),
+		"src/d/c.log": memfs.NewFile("c.log", 0644, mtime, []byte("ccc")),
+		"other.txt":   memfs.NewFile("other.txt", 0644, mtime, nil),
+	})
+}
+
+func TestCopyTreeToRealFS(t *testing.T) {
+	t.Parallel()
+	dir, err := ioutil.TempDir("", "fsutil_test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	mtime := time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC)
+	src := newCopySource(mtime)
+	dst := realfs.New()
+	target := filepath.Join(dir, "out")
+
+	var progress []string
+	var written int64
+	opts := fsutil.CopyOptions{
+		PreserveMode:  true,
+		PreserveTimes: true,
+		Progress: func(name string, info os.FileInfo, n int64) {
+			progress = append(progress, relative(target, name))
+			written += n
+		},
+	}
+	if err := fsutil.CopyTree(dst, target, src, "src", opts); err != nil {
+		t.Fatal(err)
+	}
+	expected := []string{".", "a.txt", "d", "d/b.txt", "d/c.log"}
+	if !reflect.DeepEqual(progress, expected) {
+		t.Fatalf("did not find expected progress, got %v", progress)
+	}
+	if written != 6 {
+		t.Fatalf("was expecting 6 bytes written, got %d", written)
+	}
+
+	fi, err := os.Stat(filepath.Join(target, "d", "b.txt"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fi.Mode() != 0640 || !fi.ModTime().Equal(mtime) || fi.Size() != 2 {
+		t.Fatalf("did not find expected metadata: %v %v %d", fi.Mode(), fi.ModTime(), fi.Size())
+	}
+	srcInfo, err := fsutil.Stat(src, "src/d")
+	if err != nil {
+		t.Fatal(err)
+	}
+	fi, err = os.Stat(filepath.Join(target, "d"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !fi.ModTime().Equal(srcInfo.ModTime()) {
+		t.Fatalf("was expecting directory mtime to be preserved, got %v", fi.ModTime())
+	}
+
+	// copying again fails by default
+	err = fsutil.CopyTree(dst, target, src, "src", fsutil.CopyOptions{})
+	if !errors.Is(err, fs.ErrExist) {
+		t.Fatalf("was expecting ErrExist, got %v", err)
+	}
+}
+
+func TestCopyTreeFilter(t *testing.T) {
+	t.Parallel()
+	src := newCopySource(time.Now())
+	dst := memfs.NewWithFiles(nil)
+	opts := fsutil.CopyOptions{
+		Filter: func(name string, info os.FileInfo) bool {
+			return info.IsDir() || filepath.Ext(name) == ".txt"
+		},
+	}
+	if err := fsutil.CopyTree(dst, "out", src, "src", opts); err != nil {
+		t.Fatal(err)
+	}
+	names, err := fsutil.Glob(dst, "out/**")
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := []string{"out", "out/a.txt", "out/d", "out/d/b.txt"}
+	if !reflect.DeepEqual(names, expected) {
+		t.Fatalf("did not find expected names, got %v", names)
+	}
+}
+
+func TestCopyTreeOverwrite(t *testing.T) {
+	t.Parallel()
+	old := time.Now().Add(-time.Hour)
+	cases := []struct {
+		Policy   fsutil.OverwritePolicy
+		SrcTime  time.Time
+		Expected string
+	}{
+		{fsutil.OverwriteSkip, time.Now(), "existing"},
+		{fsutil.OverwriteAlways, old.Add(-time.Hour), "a"},
+		{fsutil.OverwriteIfNewer, old.Add(-time.Hour), "existing"},
+		{fsutil.OverwriteIfNewer, time.Now(), "a"},
+	}
+	for _, c := range cases {
+		src := newCopySource(c.SrcTime)
+		dst := memfs.NewWithFiles(map[string]fs.File{
+			"out/a.txt": memfs.NewFile("a.txt", 0644, old, []byte("existing")),
+		})
+		opts := fsutil.CopyOptions{Overwrite: c.Policy}
+		if err := fsutil.CopyTree(dst, "out", src, "src", opts); err != nil {
+			t.Fatal(err)
+		}
+		b, err := fsutil.ReadFile(dst, "out/a.txt")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(b) != c.Expected {
+			t.Fatalf("policy %d: was expecting %s, got %s", c.Policy, c.Expected, b)
+		}
+		if _, err := fsutil.Stat(dst, "out/d/c.log"); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestCopyTreeSingleFile(t *testing.T) {
+	t.Parallel()
+	src := newCopySource(time.Now())
+	dst := memfs.NewWithFiles(nil)
+	if err := fsutil.CopyTree(dst, "copy.txt", src, "src/d/c.log", fsutil.CopyOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	b, err := fsutil.ReadFile(dst, "copy.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(b) != "ccc" {
+		t.Fatalf("was expecting ccc, got %s", b)
+	}
+}
+
+func TestCopyTreePreserveOwner(t *testing.T) {
+	t.Parallel()
+	src := newCopySource(time.Now())
+	if err := fsutil.Chown(src, "src/d/b.txt", 1, 2); err != nil {
+		t.Fatal(err)
+	}
+	dst := memfs.NewWithFiles(nil)
+	opts := fsutil.CopyOptions{PreserveOwner: true}
+	if err := fsutil.CopyTree(dst, "out", src, "src", opts); err != nil {
+		t.Fatal(err)
+	}
+	ident, err := fsutil.Ident(dst, "out/d/b.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ident.UID != 1 || ident.GID != 2 {
+		t.Fatalf("was expecting uid 1 and gid 2, got %+v", ident)
+	}
+}
@@ -0,0 +1,8 @@
+//go:build !plan9
+
+package fsutil
+
+import "syscall"
+
+// Reported when a walk following symbolic links finds a cycle.
+const errLoop = syscall.ELOOP
@@ -0,0 +1,7 @@
+package fsutil
+
+import "errors"
+
+// Reported when a walk following symbolic links finds a cycle. Plan 9 has no
+// error number for it.
+var errLoop = errors.New("too many levels of symbolic links")
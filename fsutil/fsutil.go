@@ -3,46 +3,51 @@ package fsutil
 
 import (
 	"errors"
-	"fmt"
 	"os"
 	"path"
 	"path/filepath"
 	"strings"
-)
-
-var errInvalidCharacterInPath = errors.New("invalid character in file path")
 
-type errNotFound string
+	"github.com/daaku/go.fs"
+)
 
-func (e errNotFound) Error() string {
-	return fmt.Sprintf("file not found: %s", string(e))
+// Returns an error that indicates the named file was not found. Prefer
+// returning a *fs.PathError with the actual operation.
+func NewErrNotFound(name string) error {
+	return &fs.PathError{Op: "open", Path: name, Err: fs.ErrNotExist}
 }
 
-// Returns an error that indicates the named file was not found.
-func NewErrNotFound(name string) error {
-	return errNotFound(name)
+// Returns an error that indicates the named file was not found or access was
+// artificially limited. Since the caller should not be able to tell the
+// difference, this is the same as NewErrNotFound.
+func NewErrLimitedNotFound(name string) error {
+	return NewErrNotFound(name)
 }
 
-type errLimitedNotFound string
+// Returns an error that indicates a write operation was attempted on the
+// named file in a read-only File System. Prefer returning a *fs.PathError
+// with the actual operation.
+func NewErrReadOnly(name string) error {
+	return &fs.PathError{Op: "open", Path: name, Err: fs.ErrReadOnly}
+}
 
-func (e errLimitedNotFound) Error() string {
-	return fmt.Sprintf("file limited or not found: %s", string(e))
+// IsReadOnly returns whether the error is known to report that a write
+// operation was attempted on a read-only File System.
+func IsReadOnly(err error) bool {
+	return errors.Is(err, fs.ErrReadOnly)
 }
 
-// Returns an error that indicates the named file was not found or access was
-// artificially limited.
-func NewErrLimitedNotFound(name string) error {
-	return errLimitedNotFound(name)
+// WantsWrite returns whether the flags for OpenFile request any kind of write
+// access.
+func WantsWrite(flag int) bool {
+	const mask = os.O_WRONLY | os.O_RDWR | os.O_APPEND | os.O_CREATE | os.O_TRUNC
+	return flag&mask != 0
 }
 
+// IsNotExist returns whether the error is known to report that a file does
+// not exist.
 func IsNotExist(err error) bool {
-	if _, ok := err.(errNotFound); ok {
-		return true
-	}
-	if _, ok := err.(errLimitedNotFound); ok {
-		return true
-	}
-	return os.IsNotExist(err)
+	return errors.Is(err, fs.ErrNotExist)
 }
 
 // Cleans path string.
@@ -50,7 +55,18 @@ func Clean(name string) (string, error) {
 	if filepath.Separator != '/' &&
 		strings.IndexRune(name, filepath.Separator) >= 0 ||
 		strings.Contains(name, "\x00") {
-		return "", errInvalidCharacterInPath
+		return "", &fs.PathError{Op: "clean", Path: name, Err: fs.ErrInvalid}
 	}
 	return filepath.FromSlash(path.Clean("/" + name)), nil
 }
+
+// Cleans the name to a slash separated path relative to the root directory,
+// which is itself named ".". This is the form used by File Systems that are
+// not backed by the real file system, like "dir/file".
+func CleanRelative(name string) string {
+	name = path.Clean("/" + name)
+	if name == "/" {
+		return "."
+	}
+	return name[1:]
+}
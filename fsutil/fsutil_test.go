@@ -0,0 +1,82 @@
+package fsutil_test
+
+import (
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/daaku/go.fs"
+	"github.com/daaku/go.fs/fsutil"
+	"github.com/daaku/go.fs/memfs"
+	"github.com/daaku/go.fs/realfs"
+)
+
+func TestErrNotFound(t *testing.T) {
+	t.Parallel()
+	err := fsutil.NewErrNotFound("foo")
+	if !fsutil.IsNotExist(err) {
+		t.Fatal("was expecting is not exist error")
+	}
+	if !os.IsNotExist(err) || !errors.Is(err, os.ErrNotExist) {
+		t.Fatal("was expecting os not exist error")
+	}
+	var pe *fs.PathError
+	if !errors.As(err, &pe) || pe.Path != "foo" {
+		t.Fatalf("was expecting path error for foo, got %v", err)
+	}
+}
+
+func TestErrReadOnly(t *testing.T) {
+	t.Parallel()
+	err := fsutil.NewErrReadOnly("foo")
+	if !fsutil.IsReadOnly(err) {
+		t.Fatal("was expecting read-only error")
+	}
+	if fsutil.IsNotExist(err) {
+		t.Fatal("was not expecting is not exist error")
+	}
+}
+
+func TestSentinelsMatchAcrossSystems(t *testing.T) {
+	t.Parallel()
+	dir, err := ioutil.TempDir("", "fsutil_test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	if err := ioutil.WriteFile(filepath.Join(dir, "foo"), nil, 0644); err != nil {
+		t.Fatal(err)
+	}
+	ms := memfs.NewWithFiles(nil)
+	if _, err := ms.Create("foo"); err != nil {
+		t.Fatal(err)
+	}
+
+	cases := []struct {
+		System fs.System
+		Root   string
+	}{
+		{realfs.New(), dir},
+		{ms, ""},
+	}
+	for _, c := range cases {
+		_, err := c.System.Open(filepath.Join(c.Root, "missing"))
+		if !errors.Is(err, fs.ErrNotExist) {
+			t.Fatalf("was expecting ErrNotExist, got %v", err)
+		}
+		err = c.System.Mkdir(filepath.Join(c.Root, "foo"), 0755)
+		if !errors.Is(err, fs.ErrExist) {
+			t.Fatalf("was expecting ErrExist, got %v", err)
+		}
+		_, err = c.System.Create(filepath.Join(c.Root, "foo", "bar"))
+		if !errors.Is(err, fs.ErrNotDir) {
+			t.Fatalf("was expecting ErrNotDir, got %v", err)
+		}
+		var pe *fs.PathError
+		if !errors.As(err, &pe) {
+			t.Fatalf("was expecting PathError, got %T", err)
+		}
+	}
+}
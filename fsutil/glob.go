@@ -0,0 +1,251 @@
+package fsutil
+
+import (
+	iofs "io/fs"
+	"path"
+	"strings"
+
+	"github.com/daaku/go.fs"
+)
+
+// ErrBadPattern indicates a pattern was malformed.
+var ErrBadPattern = path.ErrBadPattern
+
+// Match reports whether name matches the pattern. The pattern syntax is that
+// of path.Match, with a few additions:
+//
+//	**        as a complete path segment, matches zero or more segments
+//	[!class]  same as [^class]
+//	{a,b}     matches either alternative, alternatives may be nested
+//	!pattern  a leading ! matches names that do not match the pattern
+//
+// The only possible returned error is ErrBadPattern, when pattern is
+// malformed.
+func Match(pattern, name string) (bool, error) {
+	negate := strings.HasPrefix(pattern, "!")
+	if negate {
+		pattern = pattern[1:]
+	}
+	alternatives, err := compile(pattern)
+	if err != nil {
+		return false, err
+	}
+	segments := strings.Split(name, "/")
+	for _, alt := range alternatives {
+		match, err := matchSegments(alt, segments)
+		if err != nil {
+			return false, err
+		}
+		if match {
+			return !negate, nil
+		}
+	}
+	return negate, nil
+}
+
+// Glob returns the names of all files in the System matching the pattern, in
+// the lexical order they are found by WalkDir. The syntax is the same as
+// Match. The walk starts at the longest leading part of the pattern without
+// any special characters, and names are returned relative to the System in
+// the same form as the pattern. Negated patterns walk the entire System
+// starting at ".". I/O errors are ignored, and the only possible returned
+// error is ErrBadPattern.
+func Glob(s fs.System, pattern string) ([]string, error) {
+	negate := strings.HasPrefix(pattern, "!")
+	rest := pattern
+	if negate {
+		rest = pattern[1:]
+	}
+	alternatives, err := compile(rest)
+	if err != nil {
+		return nil, err
+	}
+
+	// when the pattern has no ** we can avoid walking deeper than the longest
+	// alternative
+	depth := 0
+	for _, alt := range alternatives {
+		for _, segment := range alt {
+			if segment == "**" {
+				depth = -1
+				break
+			}
+		}
+		if depth == -1 {
+			break
+		}
+		if len(alt) > depth {
+			depth = len(alt)
+		}
+	}
+
+	root := "."
+	if !negate {
+		root = globRoot(rest)
+	}
+
+	var matches []string
+	WalkDir(s, root, func(name string, d iofs.DirEntry, err error) error {
+		if err != nil {
+			return nil
+		}
+		if name != "." {
+			match, _ := Match(pattern, name)
+			if match {
+				matches = append(matches, name)
+			}
+		}
+		if d.IsDir() && depth != -1 && name != root {
+			if len(strings.Split(name, "/")) >= depth {
+				return SkipDir
+			}
+		}
+		return nil
+	})
+	return matches, nil
+}
+
+// Returns the leading part of the pattern without any special characters.
+func globRoot(pattern string) string {
+	segments := strings.Split(pattern, "/")
+	for ix, segment := range segments {
+		if strings.ContainsAny(segment, `*?[{\`) {
+			segments = segments[:ix]
+			break
+		}
+	}
+	switch {
+	case len(segments) == 0:
+		return "."
+	case len(segments) == 1 && segments[0] == "":
+		return "/"
+	}
+	return strings.Join(segments, "/")
+}
+
+// Expands the braces in the pattern, and splits the alternatives into
+// segments ready for matching. It also validates the segments.
+func compile(pattern string) ([][]string, error) {
+	expanded, err := expand(pattern)
+	if err != nil {
+		return nil, err
+	}
+	alternatives := make([][]string, len(expanded))
+	for ix, alt := range expanded {
+		alt = negateClasses(alt)
+		segments := strings.Split(alt, "/")
+		for _, segment := range segments {
+			if _, err := path.Match(segment, ""); err != nil {
+				return nil, err
+			}
+		}
+		alternatives[ix] = segments
+	}
+	return alternatives, nil
+}
+
+// Converts [!class] to the [^class] syntax understood by path.Match.
+func negateClasses(pattern string) string {
+	b := []byte(pattern)
+	for i := 0; i < len(b); i++ {
+		switch b[i] {
+		case '\\':
+			i++
+		case '[':
+			if i+1 < len(b) && b[i+1] == '!' {
+				b[i+1] = '^'
+			}
+			for i++; i < len(b) && b[i] != ']'; i++ {
+				if b[i] == '\\' {
+					i++
+				}
+			}
+		}
+	}
+	return string(b)
+}
+
+// Expands the first top level brace and recursively expands the results.
+func expand(pattern string) ([]string, error) {
+	start, end := -1, -1
+	var commas []int
+	depth := 0
+	inClass := false
+loop:
+	for i := 0; i < len(pattern); i++ {
+		switch c := pattern[i]; {
+		case c == '\\':
+			i++
+		case inClass:
+			if c == ']' {
+				inClass = false
+			}
+		case c == '[':
+			inClass = true
+		case c == '{':
+			if depth == 0 {
+				start = i
+			}
+			depth++
+		case c == ',' && depth == 1:
+			commas = append(commas, i)
+		case c == '}':
+			if depth == 0 {
+				return nil, ErrBadPattern
+			}
+			depth--
+			if depth == 0 {
+				end = i
+				break loop
+			}
+		}
+	}
+	if depth != 0 {
+		return nil, ErrBadPattern
+	}
+	if start == -1 {
+		return []string{pattern}, nil
+	}
+
+	prefix, suffix := pattern[:start], pattern[end+1:]
+	var expanded []string
+	from := start + 1
+	for _, to := range append(commas, end) {
+		more, err := expand(prefix + pattern[from:to] + suffix)
+		if err != nil {
+			return nil, err
+		}
+		expanded = append(expanded, more...)
+		from = to + 1
+	}
+	return expanded, nil
+}
+
+func matchSegments(pattern, name []string) (bool, error) {
+	for len(pattern) > 0 {
+		if pattern[0] == "**" {
+			for len(pattern) > 0 && pattern[0] == "**" {
+				pattern = pattern[1:]
+			}
+			if len(pattern) == 0 {
+				return true, nil
+			}
+			for ix := range name {
+				match, err := matchSegments(pattern, name[ix:])
+				if err != nil || match {
+					return match, err
+				}
+			}
+			return false, nil
+		}
+		if len(name) == 0 {
+			return false, nil
+		}
+		match, err := path.Match(pattern[0], name[0])
+		if err != nil || !match {
+			return false, err
+		}
+		pattern, name = pattern[1:], name[1:]
+	}
+	return len(name) == 0, nil
+}
@@ -0,0 +1,95 @@
+package fsutil_test
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/daaku/go.fs"
+	"github.com/daaku/go.fs/fsutil"
+	"github.com/daaku/go.fs/memfs"
+)
+
+func TestMatch(t *testing.T) {
+	t.Parallel()
+	cases := []struct {
+		Pattern string
+		Name    string
+		Match   bool
+	}{
+		{"*.html", "a.html", true},
+		{"*.html", "d/a.html", false},
+		{"**/*.html", "a.html", true},
+		{"**/*.html", "d/e/a.html", true},
+		{"**/*.html", "/abs/d/a.html", true},
+		{"d/**", "d", true},
+		{"d/**", "d/e/f", true},
+		{"d/**/f", "d/f", true},
+		{"d/**/f", "d/e/g/f", true},
+		{"d/**/f", "d/e/g", false},
+		{"[a-c].txt", "b.txt", true},
+		{"[!a-c].txt", "b.txt", false},
+		{"[^a-c].txt", "d.txt", true},
+		{"*.{html,css}", "a.css", true},
+		{"*.{html,css}", "a.js", false},
+		{"{a,b/{c,d}}/x", "b/d/x", true},
+		{"{a,b/{c,d}}/x", "b/e/x", false},
+		{"!*.html", "a.html", false},
+		{"!*.html", "a.css", true},
+		{`\*.txt`, "*.txt", true},
+		{`\*.txt`, "a.txt", false},
+		{`\[!a].txt`, "[!a].txt", true},
+	}
+	for _, c := range cases {
+		match, err := fsutil.Match(c.Pattern, c.Name)
+		if err != nil {
+			t.Fatalf("%s: %v", c.Pattern, err)
+		}
+		if match != c.Match {
+			t.Fatalf("%s against %s: was expecting %v", c.Pattern, c.Name, c.Match)
+		}
+	}
+}
+
+func TestMatchBadPattern(t *testing.T) {
+	t.Parallel()
+	for _, p := range []string{"[a", "{a,b", "a}", "d/**/[", `a\`} {
+		if _, err := fsutil.Match(p, "a"); err != fsutil.ErrBadPattern {
+			t.Fatalf("%s: was expecting ErrBadPattern, got %v", p, err)
+		}
+	}
+}
+
+func TestGlob(t *testing.T) {
+	t.Parallel()
+	files := make(map[string]fs.File)
+	for _, name := range []string{"a.html", "b.css", "d/c.html", "d/e/f.html", "g/h.txt"} {
+		files[name] = memfs.NewFile(name, 0644, time.Now(), nil)
+	}
+	s := memfs.NewWithFiles(files)
+	cases := []struct {
+		Pattern string
+		Names   []string
+	}{
+		{"*.html", []string{"a.html"}},
+		{"**/*.html", []string{"a.html", "d/c.html", "d/e/f.html"}},
+		{"d/**/*.html", []string{"d/c.html", "d/e/f.html"}},
+		{"*.{css,txt}", []string{"b.css"}},
+		{"*/*.{css,txt}", []string{"g/h.txt"}},
+		{"!**/*.html", []string{"b.css", "d", "d/e", "g", "g/h.txt"}},
+		{"d/c.html", []string{"d/c.html"}},
+		{"missing/*", nil},
+	}
+	for _, c := range cases {
+		names, err := fsutil.Glob(s, c.Pattern)
+		if err != nil {
+			t.Fatalf("%s: %v", c.Pattern, err)
+		}
+		if !reflect.DeepEqual(names, c.Names) {
+			t.Fatalf("%s: was expecting %v, got %v", c.Pattern, c.Names, names)
+		}
+	}
+	if _, err := fsutil.Glob(s, "{a"); err != fsutil.ErrBadPattern {
+		t.Fatalf("was expecting ErrBadPattern, got %v", err)
+	}
+}
@@ -0,0 +1,73 @@
+package fsutil
+
+import (
+	"math/rand"
+	"os"
+	"path"
+	"strconv"
+	"strings"
+
+	"github.com/daaku/go.fs"
+)
+
+// MkdirTemp creates a new directory in the directory dir and returns its
+// name. The name is generated by taking pattern and replacing the last "*"
+// with a random string, or appending one if there is no "*". If dir is the
+// empty string, "." is used.
+func MkdirTemp(s fs.System, dir, pattern string) (string, error) {
+	prefix, suffix, err := splitTempPattern("mkdirtemp", pattern)
+	if err != nil {
+		return "", err
+	}
+	for try := 0; ; try++ {
+		name := tempName(dir, prefix, suffix)
+		err := s.Mkdir(name, 0700)
+		if err == nil {
+			return name, nil
+		}
+		if try < 10000 && os.IsExist(err) {
+			continue
+		}
+		return "", err
+	}
+}
+
+// CreateTemp creates a new file in the directory dir, opened for reading and
+// writing, and returns it along with its name since a File does not know its
+// own name. The name is generated from the pattern like MkdirTemp. If dir is
+// the empty string, "." is used. It is the caller's responsibility to remove
+// the file when it is no longer needed.
+func CreateTemp(s fs.System, dir, pattern string) (fs.File, string, error) {
+	prefix, suffix, err := splitTempPattern("createtemp", pattern)
+	if err != nil {
+		return nil, "", err
+	}
+	for try := 0; ; try++ {
+		name := tempName(dir, prefix, suffix)
+		f, err := s.OpenFile(name, os.O_RDWR|os.O_CREATE|os.O_EXCL, 0600)
+		if err == nil {
+			return f, name, nil
+		}
+		if try < 10000 && os.IsExist(err) {
+			continue
+		}
+		return nil, "", err
+	}
+}
+
+func splitTempPattern(op, pattern string) (prefix, suffix string, err error) {
+	if strings.Contains(pattern, "/") {
+		return "", "", &fs.PathError{Op: op, Path: pattern, Err: fs.ErrInvalid}
+	}
+	if pos := strings.LastIndex(pattern, "*"); pos != -1 {
+		return pattern[:pos], pattern[pos+1:], nil
+	}
+	return pattern, "", nil
+}
+
+func tempName(dir, prefix, suffix string) string {
+	if dir == "" {
+		dir = "."
+	}
+	return path.Join(dir, prefix+strconv.FormatUint(uint64(rand.Uint32()), 10)+suffix)
+}
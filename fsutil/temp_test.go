@@ -0,0 +1,77 @@
+package fsutil_test
+
+import (
+	"errors"
+	"io/ioutil"
+	"os"
+	"path"
+	"strings"
+	"testing"
+
+	"github.com/daaku/go.fs"
+	"github.com/daaku/go.fs/fsutil"
+	"github.com/daaku/go.fs/memfs"
+	"github.com/daaku/go.fs/realfs"
+)
+
+func TestMkdirTemp(t *testing.T) {
+	t.Parallel()
+	s := memfs.NewWithFiles(nil)
+	a, err := fsutil.MkdirTemp(s, "", "foo*bar")
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := fsutil.MkdirTemp(s, "", "foo*bar")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if a == b {
+		t.Fatalf("was expecting different names, got %s", a)
+	}
+	if !strings.HasPrefix(a, "foo") || !strings.HasSuffix(a, "bar") {
+		t.Fatalf("did not find expected name, got %s", a)
+	}
+	fi, err := fsutil.Stat(s, a)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !fi.IsDir() {
+		t.Fatal("was expecting a directory")
+	}
+	if _, err := fsutil.MkdirTemp(s, "", "a/b"); !errors.Is(err, fs.ErrInvalid) {
+		t.Fatalf("was expecting ErrInvalid, got %v", err)
+	}
+	if _, err := fsutil.MkdirTemp(s, "missing", ""); !s.IsNotExist(err) {
+		t.Fatalf("was expecting is not exist error, got %v", err)
+	}
+}
+
+func TestCreateTemp(t *testing.T) {
+	t.Parallel()
+	dir, err := ioutil.TempDir("", "fsutil_test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	s := realfs.New()
+	f, name, err := fsutil.CreateTemp(s, dir, "*.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if path.Dir(name) != dir || path.Ext(name) != ".txt" {
+		t.Fatalf("did not find expected name, got %s", name)
+	}
+	if _, err := f.WriteString("foo"); err != nil {
+		t.Fatal(err)
+	}
+	if err := f.Close(); err != nil {
+		t.Fatal(err)
+	}
+	b, err := fsutil.ReadFile(s, name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(b) != "foo" {
+		t.Fatalf("was expecting foo, got %s", b)
+	}
+}
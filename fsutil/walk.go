@@ -0,0 +1,186 @@
+package fsutil
+
+import (
+	iofs "io/fs"
+	"os"
+	"path"
+
+	"github.com/daaku/go.fs"
+)
+
+// SkipDir can be returned from a WalkFunc or WalkDirFunc to skip the directory
+// named in the call. When returned for a file, the remaining files in the
+// containing directory are skipped.
+var SkipDir = iofs.SkipDir
+
+// SkipAll can be returned from a WalkFunc or WalkDirFunc to stop the walk
+// without an error.
+var SkipAll = iofs.SkipAll
+
+// WalkFunc is called for every file visited by Walk. It has the same contract
+// as path/filepath.WalkFunc.
+type WalkFunc func(path string, info os.FileInfo, err error) error
+
+// WalkDirFunc is called for every file visited by WalkDir. It has the same
+// contract as io/fs.WalkDirFunc.
+type WalkDirFunc func(path string, d iofs.DirEntry, err error) error
+
+// Walker walks file trees in a System. Files are visited in lexical order,
+// and names are joined using forward slashes.
+type Walker struct {
+	System         fs.System
+	FollowSymlinks bool // descend into symbolic links to directories
+}
+
+// Walk walks the file tree rooted at root in the System, calling fn for each
+// file or directory including root. Symbolic links are not followed.
+func Walk(s fs.System, root string, fn WalkFunc) error {
+	return Walker{System: s}.Walk(root, fn)
+}
+
+// WalkDir is like Walk, but calls fn with an io/fs.DirEntry which avoids
+// stating every visited file. Symbolic links are not followed.
+func WalkDir(s fs.System, root string, fn WalkDirFunc) error {
+	return Walker{System: s}.WalkDir(root, fn)
+}
+
+// Walk walks the file tree rooted at root, calling fn for each file or
+// directory including root.
+func (w Walker) Walk(root string, fn WalkFunc) error {
+	info, err := w.stat(root)
+	if err != nil {
+		err = fn(root, nil, err)
+	} else {
+		err = w.walk(root, info, fn, nil)
+	}
+	if err == SkipDir || err == SkipAll {
+		return nil
+	}
+	return err
+}
+
+func (w Walker) walk(name string, info os.FileInfo, fn WalkFunc, parents []os.FileInfo) error {
+	if !info.IsDir() {
+		return fn(name, info, nil)
+	}
+
+	infos, err := w.readDir(name, info, parents)
+	err1 := fn(name, info, err)
+	if err != nil || err1 != nil {
+		return err1
+	}
+
+	parents = append(parents, info)
+	for _, child := range infos {
+		childName := path.Join(name, child.Name())
+		child, err := w.follow(childName, child)
+		if err != nil {
+			if err := fn(childName, child, err); err != nil && err != SkipDir {
+				return err
+			}
+			continue
+		}
+		if err := w.walk(childName, child, fn, parents); err != nil {
+			if !child.IsDir() || err != SkipDir {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// WalkDir walks the file tree rooted at root, calling fn for each file or
+// directory including root.
+func (w Walker) WalkDir(root string, fn WalkDirFunc) error {
+	info, err := w.stat(root)
+	if err != nil {
+		err = fn(root, nil, err)
+	} else {
+		err = w.walkDir(root, iofs.FileInfoToDirEntry(info), fn, nil)
+	}
+	if err == SkipDir || err == SkipAll {
+		return nil
+	}
+	return err
+}
+
+func (w Walker) walkDir(name string, d iofs.DirEntry, fn WalkDirFunc, parents []os.FileInfo) error {
+	if err := fn(name, d, nil); err != nil || !d.IsDir() {
+		if err == SkipDir && d.IsDir() {
+			err = nil
+		}
+		return err
+	}
+
+	info, err := d.Info()
+	var infos []os.FileInfo
+	if err == nil {
+		infos, err = w.readDir(name, info, parents)
+	}
+	if err != nil {
+		if err := fn(name, d, err); err != nil {
+			if err == SkipDir && d.IsDir() {
+				err = nil
+			}
+			return err
+		}
+		return nil
+	}
+
+	parents = append(parents, info)
+	for _, child := range infos {
+		childName := path.Join(name, child.Name())
+		child, err := w.follow(childName, child)
+		if err != nil {
+			if err := fn(childName, iofs.FileInfoToDirEntry(child), err); err != nil {
+				if err == SkipDir {
+					break
+				}
+				return err
+			}
+			continue
+		}
+		if err := w.walkDir(childName, iofs.FileInfoToDirEntry(child), fn, parents); err != nil {
+			if err == SkipDir {
+				break
+			}
+			return err
+		}
+	}
+	return nil
+}
+
+// Returns the FileInfo for the root of a walk.
+func (w Walker) stat(name string) (os.FileInfo, error) {
+	if w.FollowSymlinks {
+		return Stat(w.System, name)
+	}
+	return Lstat(w.System, name)
+}
+
+// Replaces the FileInfo for symbolic links with the FileInfo of the target
+// when following them. On error, the original FileInfo is returned.
+func (w Walker) follow(name string, info os.FileInfo) (os.FileInfo, error) {
+	if !w.FollowSymlinks || info.Mode()&os.ModeSymlink == 0 {
+		return info, nil
+	}
+	target, err := Stat(w.System, name)
+	if err != nil {
+		return info, err
+	}
+	return target, nil
+}
+
+// Reads the sorted directory contents, refusing to descend into a directory
+// that is already being walked, which is only possible when following
+// symbolic links.
+func (w Walker) readDir(name string, info os.FileInfo, parents []os.FileInfo) ([]os.FileInfo, error) {
+	if w.FollowSymlinks {
+		for _, p := range parents {
+			if os.SameFile(p, info) {
+				return nil, &fs.PathError{Op: "walk", Path: name, Err: errLoop}
+			}
+		}
+	}
+	return ReadDir(w.System, name)
+}
@@ -0,0 +1,256 @@
+package fsutil_test
+
+import (
+	"archive/zip"
+	"bytes"
+	"errors"
+	iofs "io/fs"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/daaku/go.fs"
+	"github.com/daaku/go.fs/fsutil"
+	"github.com/daaku/go.fs/limitfs"
+	"github.com/daaku/go.fs/memfs"
+	"github.com/daaku/go.fs/realfs"
+	"github.com/daaku/go.fs/zipfs"
+)
+
+var walkTree = map[string]string{
+	"b.txt":     "b",
+	"a/z.txt":   "z",
+	"a/c/d.txt": "d",
+	"a/b.txt":   "b",
+	"e/f.txt":   "f",
+}
+
+var walkExpected = []string{
+	".", "a", "a/b.txt", "a/c", "a/c/d.txt", "a/z.txt", "b.txt", "e", "e/f.txt",
+}
+
+type walkCase struct {
+	Name   string
+	System fs.System
+	Root   string
+}
+
+func walkCases(t *testing.T) []walkCase {
+	files := make(map[string]fs.File)
+	for name, data := range walkTree {
+		files[name] = memfs.NewFile(name, 0644, time.Now(), []byte(data))
+	}
+	ms := memfs.NewWithFiles(files)
+
+	dir, err := ioutil.TempDir("", "fsutil_test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.RemoveAll(dir) })
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	for name, data := range walkTree {
+		real := filepath.Join(dir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(real), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := ioutil.WriteFile(real, []byte(data), 0644); err != nil {
+			t.Fatal(err)
+		}
+		w, err := zw.Create(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := w.Write([]byte(data)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	zr, err := zip.NewReader(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return []walkCase{
+		{"memfs", ms, "."},
+		{"realfs", realfs.New(), dir},
+		{"zipfs", zipfs.New(zr), "."},
+		{"limitfs", limitfs.New(limitfs.Config{Root: dir, Recursive: true}, realfs.New()), "/"},
+	}
+}
+
+func relative(root, name string) string {
+	name = strings.TrimPrefix(strings.TrimPrefix(name, root), "/")
+	if name == "" {
+		return "."
+	}
+	return name
+}
+
+func TestWalk(t *testing.T) {
+	t.Parallel()
+	for _, c := range walkCases(t) {
+		var walked, walkedDir []string
+		err := fsutil.Walk(c.System, c.Root, func(name string, info os.FileInfo, err error) error {
+			if err != nil {
+				return err
+			}
+			walked = append(walked, relative(c.Root, name))
+			return nil
+		})
+		if err != nil {
+			t.Fatalf("%s: %v", c.Name, err)
+		}
+		err = fsutil.WalkDir(c.System, c.Root, func(name string, d iofs.DirEntry, err error) error {
+			if err != nil {
+				return err
+			}
+			walkedDir = append(walkedDir, relative(c.Root, name))
+			return nil
+		})
+		if err != nil {
+			t.Fatalf("%s: %v", c.Name, err)
+		}
+		if !reflect.DeepEqual(walked, walkExpected) {
+			t.Fatalf("%s: Walk visited %v", c.Name, walked)
+		}
+		if !reflect.DeepEqual(walkedDir, walkExpected) {
+			t.Fatalf("%s: WalkDir visited %v", c.Name, walkedDir)
+		}
+	}
+}
+
+func TestWalkSkip(t *testing.T) {
+	t.Parallel()
+	expected := []string{".", "a", "a/b.txt", "b.txt", "e"}
+	for _, c := range walkCases(t) {
+		var walked, walkedDir []string
+		err := fsutil.Walk(c.System, c.Root, func(name string, info os.FileInfo, err error) error {
+			name = relative(c.Root, name)
+			walked = append(walked, name)
+			switch name {
+			case "a/b.txt":
+				return fsutil.SkipDir
+			case "e":
+				return fsutil.SkipAll
+			}
+			return nil
+		})
+		if err != nil {
+			t.Fatalf("%s: %v", c.Name, err)
+		}
+		err = fsutil.WalkDir(c.System, c.Root, func(name string, d iofs.DirEntry, err error) error {
+			name = relative(c.Root, name)
+			walkedDir = append(walkedDir, name)
+			switch name {
+			case "a/b.txt":
+				return fsutil.SkipDir
+			case "e":
+				return fsutil.SkipAll
+			}
+			return nil
+		})
+		if err != nil {
+			t.Fatalf("%s: %v", c.Name, err)
+		}
+		if !reflect.DeepEqual(walked, expected) {
+			t.Fatalf("%s: Walk visited %v", c.Name, walked)
+		}
+		if !reflect.DeepEqual(walkedDir, expected) {
+			t.Fatalf("%s: WalkDir visited %v", c.Name, walkedDir)
+		}
+	}
+}
+
+func TestWalkError(t *testing.T) {
+	t.Parallel()
+	s := memfs.NewWithFiles(nil)
+	errStop := errors.New("stop")
+	err := fsutil.Walk(s, "missing", func(name string, info os.FileInfo, err error) error {
+		if !s.IsNotExist(err) {
+			t.Fatalf("was expecting is not exist error, got %v", err)
+		}
+		return errStop
+	})
+	if err != errStop {
+		t.Fatalf("was expecting stop error, got %v", err)
+	}
+	err = fsutil.WalkDir(s, "missing", func(name string, d iofs.DirEntry, err error) error {
+		if !s.IsNotExist(err) {
+			t.Fatalf("was expecting is not exist error, got %v", err)
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestWalkFollowSymlinks(t *testing.T) {
+	t.Parallel()
+	dir, err := ioutil.TempDir("", "fsutil_test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	if err := os.MkdirAll(filepath.Join(dir, "a"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(filepath.Join(dir, "a", "f"), nil, 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Symlink(filepath.Join(dir, "a"), filepath.Join(dir, "l")); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Symlink("..", filepath.Join(dir, "a", "up")); err != nil {
+		t.Fatal(err)
+	}
+
+	w := fsutil.Walker{System: realfs.New(), FollowSymlinks: true}
+	var walked []string
+	var loops int
+	err = w.Walk(dir, func(name string, info os.FileInfo, err error) error {
+		if errors.Is(err, syscall.ELOOP) {
+			loops++
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		walked = append(walked, relative(dir, name))
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := []string{".", "a", "a/f", "l", "l/f"}
+	if !reflect.DeepEqual(walked, expected) {
+		t.Fatalf("did not find expected walk, got %v", walked)
+	}
+	if loops != 2 {
+		t.Fatalf("was expecting 2 loops, got %d", loops)
+	}
+
+	walked = nil
+	err = fsutil.WalkDir(realfs.New(), dir, func(name string, d iofs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		walked = append(walked, relative(dir, name))
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected = []string{".", "a", "a/f", "a/up", "l"}
+	if !reflect.DeepEqual(walked, expected) {
+		t.Fatalf("did not find expected walk, got %v", walked)
+	}
+}
@@ -5,7 +5,10 @@ package limitfs
 import (
 	"os"
 	"path"
+	"path/filepath"
 	"strings"
+	"sync"
+	"time"
 
 	"github.com/daaku/go.fs"
 	"github.com/daaku/go.fs/fsutil"
@@ -15,7 +18,8 @@ import (
 type Config struct {
 	Root      string // used as the root of the File System
 	Recursive bool   // control access to nested directories
-	Glob      string // limit by a glob pattern
+	Glob      string // limit by a pattern, see fsutil.Match
+	ReadOnly  bool   // disallow all write operations
 }
 
 type system struct {
@@ -24,47 +28,414 @@ type system struct {
 }
 
 func (s system) Open(name string) (fs.File, error) {
-	cleaned, err := fsutil.Clean(name)
+	final, err := s.resolve("open", name)
 	if err != nil {
 		return nil, err
 	}
+	f, err := s.System.Open(final)
+	if err != nil {
+		return nil, err
+	}
+	return s.wrap(name, final, f)
+}
+
+func (s system) IsNotExist(err error) bool {
+	return fsutil.IsNotExist(err)
+}
 
-	if !s.Config.Recursive && strings.ContainsRune(cleaned, '/') {
-		return nil, fsutil.NewErrLimitedNotFound(name)
+func (s system) Stat(name string) (os.FileInfo, error) {
+	final, err := s.resolve("stat", name)
+	if err != nil {
+		return nil, err
 	}
+	fi, err := fsutil.Stat(s.System, final)
+	if err != nil {
+		return nil, err
+	}
+	return s.check("stat", name, final, fi)
+}
 
-	final := path.Join(s.Config.Root, cleaned)
-	f, err := s.System.Open(final)
+func (s system) Lstat(name string) (os.FileInfo, error) {
+	final, err := s.resolve("lstat", name)
 	if err != nil {
 		return nil, err
 	}
+	fi, err := fsutil.Lstat(s.System, final)
+	if err != nil {
+		return nil, err
+	}
+	return s.check("lstat", name, final, fi)
+}
 
-	if s.Config.Glob != "" {
-		fi, err := f.Stat()
-		if err != nil {
+// ReadDir filters the listing from the underlying System by the Glob.
+func (s system) ReadDir(name string) ([]os.FileInfo, error) {
+	final, err := s.resolve("readdir", name)
+	if err != nil {
+		return nil, err
+	}
+	infos, err := fsutil.ReadDir(s.System, final)
+	if err != nil {
+		return nil, err
+	}
+	if s.Config.Glob == "" {
+		return infos, nil
+	}
+	return dir{Path: final, Glob: s.Config.Glob}.filter(infos)
+}
+
+func (s system) ReadFile(name string) ([]byte, error) {
+	final, err := s.resolve("open", name)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.match("open", name, final); err != nil {
+		return nil, err
+	}
+	return fsutil.ReadFile(s.System, final)
+}
+
+func (s system) WriteFile(name string, data []byte, perm os.FileMode) error {
+	final, err := s.writable("open", name)
+	if err != nil {
+		return err
+	}
+	if err := s.match("open", name, final); err != nil {
+		return err
+	}
+	return fsutil.WriteFile(s.System, final, data, perm)
+}
+
+// Symlink passes oldname through as is, the link target is not resolved
+// against the Root.
+func (s system) Symlink(oldname, newname string) error {
+	final, err := s.writable("symlink", newname)
+	if err != nil {
+		return err
+	}
+	if err := s.match("symlink", newname, final); err != nil {
+		return err
+	}
+	return fsutil.Symlink(s.System, oldname, final)
+}
+
+func (s system) Readlink(name string) (string, error) {
+	final, err := s.resolve("readlink", name)
+	if err != nil {
+		return "", err
+	}
+	if err := s.match("readlink", name, final); err != nil {
+		return "", err
+	}
+	return fsutil.Readlink(s.System, final)
+}
+
+func (s system) Chmod(name string, mode os.FileMode) error {
+	final, err := s.visible("chmod", name)
+	if err != nil {
+		return err
+	}
+	return fsutil.Chmod(s.System, final, mode)
+}
+
+func (s system) Chown(name string, uid, gid int) error {
+	final, err := s.visible("chown", name)
+	if err != nil {
+		return err
+	}
+	return fsutil.Chown(s.System, final, uid, gid)
+}
+
+func (s system) Chtimes(name string, atime time.Time, mtime time.Time) error {
+	final, err := s.visible("chtimes", name)
+	if err != nil {
+		return err
+	}
+	return fsutil.Chtimes(s.System, final, atime, mtime)
+}
+
+func (s system) Ident(name string) (fs.Ident, error) {
+	final, err := s.readable("ident", name)
+	if err != nil {
+		return fs.Ident{}, err
+	}
+	return fsutil.Ident(s.System, final)
+}
+
+func (s system) Times(name string) (fs.Times, error) {
+	final, err := s.readable("times", name)
+	if err != nil {
+		return fs.Times{}, err
+	}
+	return fsutil.Times(s.System, final)
+}
+
+func (s system) Truncate(name string, size int64) error {
+	final, err := s.visible("truncate", name)
+	if err != nil {
+		return err
+	}
+	return fsutil.Truncate(s.System, final, size)
+}
+
+func (s system) Getxattr(name, attr string) ([]byte, error) {
+	final, err := s.readable("getxattr", name)
+	if err != nil {
+		return nil, err
+	}
+	return fsutil.Getxattr(s.System, final, attr)
+}
+
+func (s system) Setxattr(name, attr string, data []byte) error {
+	final, err := s.visible("setxattr", name)
+	if err != nil {
+		return err
+	}
+	return fsutil.Setxattr(s.System, final, attr, data)
+}
+
+func (s system) Listxattr(name string) ([]string, error) {
+	final, err := s.readable("listxattr", name)
+	if err != nil {
+		return nil, err
+	}
+	return fsutil.Listxattr(s.System, final)
+}
+
+func (s system) Removexattr(name, attr string) error {
+	final, err := s.visible("removexattr", name)
+	if err != nil {
+		return err
+	}
+	return fsutil.Removexattr(s.System, final, attr)
+}
+
+// Watch forwards to the underlying System, translating the names and dropping
+// Events for files hidden by the Config. Since removed files cannot be
+// inspected, removing a directory that does not match the Glob is not
+// reported.
+func (s system) Watch(name string, recursive bool) (fs.Watcher, error) {
+	final, err := s.readable("watch", name)
+	if err != nil {
+		return nil, err
+	}
+	inner, err := fsutil.Watch(s.System, final, recursive && s.Config.Recursive)
+	if err != nil {
+		return nil, err
+	}
+	w := &watcher{
+		system: s,
+		inner:  inner,
+		name:   name,
+		final:  final,
+		events: make(chan fs.Event),
+		errors: make(chan error),
+		done:   make(chan struct{}),
+	}
+	go w.run()
+	return w, nil
+}
+
+func (s system) Create(name string) (fs.File, error) {
+	return s.OpenFile(name, os.O_RDWR|os.O_CREATE|os.O_TRUNC, 0666)
+}
+
+func (s system) OpenFile(name string, flag int, perm os.FileMode) (fs.File, error) {
+	if fsutil.WantsWrite(flag) && s.Config.ReadOnly {
+		return nil, &fs.PathError{Op: "open", Path: name, Err: fs.ErrReadOnly}
+	}
+	final, err := s.resolve("open", name)
+	if err != nil {
+		return nil, err
+	}
+	// check before creating or truncating, wrap will check again when reading
+	if fsutil.WantsWrite(flag) {
+		if err := s.match("open", name, final); err != nil {
 			return nil, err
 		}
-		if fi.IsDir() {
-			return dir{
-				File: f,
-				Path: final,
-				Glob: s.Config.Glob,
-			}, nil
+	}
+	f, err := s.System.OpenFile(final, flag, perm)
+	if err != nil {
+		return nil, err
+	}
+	return s.wrap(name, final, f)
+}
+
+func (s system) Mkdir(name string, perm os.FileMode) error {
+	final, err := s.writable("mkdir", name)
+	if err != nil {
+		return err
+	}
+	return s.System.Mkdir(final, perm)
+}
+
+func (s system) MkdirAll(name string, perm os.FileMode) error {
+	final, err := s.writable("mkdir", name)
+	if err != nil {
+		return err
+	}
+	return s.System.MkdirAll(final, perm)
+}
+
+func (s system) Remove(name string) error {
+	final, err := s.writable("remove", name)
+	if err != nil {
+		return err
+	}
+	if _, err := s.Lstat(name); err != nil {
+		return err
+	}
+	return s.System.Remove(final)
+}
+
+// RemoveAll only removes files that are visible through the Config. If some
+// of the children are hidden, the containing directories are left behind and
+// an error is returned.
+func (s system) RemoveAll(name string) error {
+	final, err := s.writable("remove", name)
+	if err != nil {
+		return err
+	}
+	if s.Config.Glob == "" && s.Config.Recursive {
+		return s.System.RemoveAll(final)
+	}
+
+	fi, err := s.Lstat(name)
+	if err != nil {
+		if s.IsNotExist(err) {
+			return nil
 		}
-		match, err := path.Match(s.Config.Glob, final)
+		return err
+	}
+	if fi.IsDir() {
+		infos, err := s.ReadDir(name)
 		if err != nil {
-			return nil, err
+			return err
 		}
-		if !match {
-			return nil, fsutil.NewErrLimitedNotFound(name)
+		for _, child := range infos {
+			if err := s.RemoveAll(path.Join(name, child.Name())); err != nil {
+				return err
+			}
 		}
 	}
+	return s.System.Remove(final)
+}
 
-	return f, nil
+func (s system) Rename(oldname, newname string) error {
+	oldfinal, err := s.writable("rename", oldname)
+	if err != nil {
+		return err
+	}
+	newfinal, err := s.writable("rename", newname)
+	if err != nil {
+		return err
+	}
+	fi, err := s.Lstat(oldname)
+	if err != nil {
+		return err
+	}
+	if !fi.IsDir() {
+		if err := s.match("rename", newname, newfinal); err != nil {
+			return err
+		}
+	}
+	return s.System.Rename(oldfinal, newfinal)
 }
 
-func (s system) IsNotExist(err error) bool {
-	return fsutil.IsNotExist(err)
+// Resolves the given name to the path in the underlying System, enforcing the
+// Recursive setting.
+func (s system) resolve(op, name string) (string, error) {
+	cleaned, err := fsutil.Clean(name)
+	if err != nil {
+		return "", err
+	}
+	if !s.Config.Recursive && strings.ContainsRune(cleaned[1:], '/') {
+		return "", &fs.PathError{Op: op, Path: name, Err: fs.ErrNotExist}
+	}
+	return path.Join(s.Config.Root, cleaned), nil
+}
+
+// Like resolve, but also enforces the ReadOnly setting.
+func (s system) writable(op, name string) (string, error) {
+	if s.Config.ReadOnly {
+		return "", &fs.PathError{Op: op, Path: name, Err: fs.ErrReadOnly}
+	}
+	return s.resolve(op, name)
+}
+
+// Like writable, but also ensures the existing file is visible.
+func (s system) visible(op, name string) (string, error) {
+	if s.Config.ReadOnly {
+		return "", &fs.PathError{Op: op, Path: name, Err: fs.ErrReadOnly}
+	}
+	return s.readable(op, name)
+}
+
+// Like resolve, but also ensures the existing file is visible.
+func (s system) readable(op, name string) (string, error) {
+	final, err := s.resolve(op, name)
+	if err != nil {
+		return "", err
+	}
+	fi, err := fsutil.Stat(s.System, final)
+	if err != nil {
+		return "", err
+	}
+	if _, err := s.check(op, name, final, fi); err != nil {
+		return "", err
+	}
+	return final, nil
+}
+
+// Checks if a file at the final path is allowed by the Glob.
+func (s system) match(op, name, final string) error {
+	if s.Config.Glob == "" {
+		return nil
+	}
+	match, err := fsutil.Match(s.Config.Glob, final)
+	if err != nil {
+		return err
+	}
+	if !match {
+		return &fs.PathError{Op: op, Path: name, Err: fs.ErrNotExist}
+	}
+	return nil
+}
+
+// Applies the Glob to the FileInfo for the file at the final path. Directories
+// are always visible.
+func (s system) check(op, name, final string, fi os.FileInfo) (os.FileInfo, error) {
+	if fi.IsDir() {
+		return fi, nil
+	}
+	if err := s.match(op, name, final); err != nil {
+		return nil, err
+	}
+	return fi, nil
+}
+
+// Applies the Glob to an opened file, wrapping directories to filter their
+// contents.
+func (s system) wrap(name, final string, f fs.File) (fs.File, error) {
+	if s.Config.Glob == "" {
+		return f, nil
+	}
+	fi, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return nil, err
+	}
+	if fi.IsDir() {
+		return dir{
+			File: f,
+			Path: final,
+			Glob: s.Config.Glob,
+		}, nil
+	}
+	if err := s.match("open", name, final); err != nil {
+		f.Close()
+		return nil, err
+	}
+	return f, nil
 }
 
 type dir struct {
@@ -99,10 +470,16 @@ func (d dir) Readdir(count int) (fis []os.FileInfo, err error) {
 	return
 }
 
+// Applies the Glob to the listing. Directories are always visible, which
+// allows walking into them when the Glob uses **.
 func (d dir) filter(given []os.FileInfo) (final []os.FileInfo, err error) {
 	for _, fi := range given {
+		if fi.IsDir() {
+			final = append(final, fi)
+			continue
+		}
 		p := path.Join(d.Path, fi.Name())
-		match, err := path.Match(d.Glob, p)
+		match, err := fsutil.Match(d.Glob, p)
 		if err != nil {
 			return nil, err
 		}
@@ -121,6 +498,88 @@ func (d dir) Readdirnames(count int) (names []string, err error) {
 	return names, err
 }
 
+type watcher struct {
+	system    system
+	inner     fs.Watcher
+	name      string // as given to Watch
+	final     string // in the underlying System
+	events    chan fs.Event
+	errors    chan error
+	done      chan struct{}
+	closeOnce sync.Once
+}
+
+func (w *watcher) Events() <-chan fs.Event {
+	return w.events
+}
+
+func (w *watcher) Errors() <-chan error {
+	return w.errors
+}
+
+func (w *watcher) Close() error {
+	var err error
+	w.closeOnce.Do(func() {
+		close(w.done)
+		err = w.inner.Close()
+	})
+	return err
+}
+
+// Forwards from the inner Watcher until it is closed.
+func (w *watcher) run() {
+	defer close(w.errors)
+	defer close(w.events)
+	events, errors := w.inner.Events(), w.inner.Errors()
+	for events != nil || errors != nil {
+		select {
+		case ev, ok := <-events:
+			if !ok {
+				events = nil
+				continue
+			}
+			ev, ok = w.translate(ev)
+			if !ok {
+				continue
+			}
+			select {
+			case w.events <- ev:
+			case <-w.done:
+				return
+			}
+		case err, ok := <-errors:
+			if !ok {
+				errors = nil
+				continue
+			}
+			select {
+			case w.errors <- err:
+			case <-w.done:
+				return
+			}
+		}
+	}
+}
+
+// Translates an Event from the underlying System, returning false if the
+// file is not visible through the Config.
+func (w *watcher) translate(ev fs.Event) (fs.Event, bool) {
+	rel := strings.TrimPrefix(filepath.ToSlash(ev.Name), w.final)
+	rel = strings.TrimPrefix(rel, "/")
+	name := path.Join(w.name, rel)
+	final, err := w.system.resolve("watch", name)
+	if err != nil {
+		return ev, false
+	}
+	if err := w.system.match("watch", name, final); err != nil {
+		fi, err := fsutil.Lstat(w.system.System, final)
+		if err != nil || !fi.IsDir() {
+			return ev, false
+		}
+	}
+	return fs.Event{Name: name, Op: ev.Op}, true
+}
+
 // Create a wrapped fs.System that limits access based on the provided Config.
 func New(c Config, s fs.System) fs.System {
 	return system{Config: c, System: s}
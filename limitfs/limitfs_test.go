@@ -0,0 +1,291 @@
+package limitfs_test
+
+import (
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/daaku/go.fs"
+	"github.com/daaku/go.fs/fstest"
+	"github.com/daaku/go.fs/fsutil"
+	"github.com/daaku/go.fs/limitfs"
+	"github.com/daaku/go.fs/memfs"
+	"github.com/daaku/go.fs/realfs"
+)
+
+func newMemSystem() fs.System {
+	return memfs.NewWithFiles(map[string]fs.File{
+		"root/foo.txt":   memfs.NewFile("foo.txt", os.FileMode(0644), time.Now(), nil),
+		"root/d/bar.txt": memfs.NewFile("bar.txt", os.FileMode(0644), time.Now(), nil),
+	})
+}
+
+func TestNotRecursive(t *testing.T) {
+	t.Parallel()
+	s := limitfs.New(limitfs.Config{Root: "root"}, newMemSystem())
+	if _, err := s.Open("foo.txt"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.Open("d/bar.txt"); !s.IsNotExist(err) {
+		t.Fatalf("was expecting is not exist error, got %v", err)
+	}
+}
+
+func TestReadOnly(t *testing.T) {
+	t.Parallel()
+	s := limitfs.New(
+		limitfs.Config{Root: "root", Recursive: true, ReadOnly: true},
+		newMemSystem(),
+	)
+	if _, err := s.Create("baz.txt"); !fsutil.IsReadOnly(err) {
+		t.Fatalf("was expecting read-only error, got %v", err)
+	}
+	if err := s.Remove("foo.txt"); !fsutil.IsReadOnly(err) {
+		t.Fatalf("was expecting read-only error, got %v", err)
+	}
+	if _, err := s.OpenFile("foo.txt", os.O_RDONLY, 0); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestCreateWithGlob(t *testing.T) {
+	t.Parallel()
+	s := limitfs.New(
+		limitfs.Config{Root: "root", Recursive: true, Glob: "root/*.txt"},
+		newMemSystem(),
+	)
+	if _, err := s.Create("baz.txt"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.Create("baz.html"); !s.IsNotExist(err) {
+		t.Fatalf("was expecting is not exist error, got %v", err)
+	}
+	if err := s.Rename("baz.txt", "baz.html"); !s.IsNotExist(err) {
+		t.Fatalf("was expecting is not exist error, got %v", err)
+	}
+}
+
+func TestTruncateWithGlob(t *testing.T) {
+	t.Parallel()
+	ms := newMemSystem()
+	if err := fsutil.WriteFile(ms, "root/hidden.html", []byte("hidden"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	s := limitfs.New(
+		limitfs.Config{Root: "root", Recursive: true, Glob: "root/*.txt"},
+		ms,
+	)
+	if _, err := s.OpenFile("hidden.html", os.O_WRONLY|os.O_TRUNC, 0); !s.IsNotExist(err) {
+		t.Fatalf("was expecting is not exist error, got %v", err)
+	}
+	b, err := fsutil.ReadFile(ms, "root/hidden.html")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(b) != "hidden" {
+		t.Fatalf("was expecting the hidden file to be unchanged, got %q", b)
+	}
+}
+
+func TestRemoveAllWithGlob(t *testing.T) {
+	t.Parallel()
+	ms := newMemSystem()
+	if _, err := ms.Create("root/d/hidden.html"); err != nil {
+		t.Fatal(err)
+	}
+	s := limitfs.New(
+		limitfs.Config{Root: "root", Recursive: true, Glob: "root/d/*.txt"},
+		ms,
+	)
+	if err := s.RemoveAll("d"); err == nil {
+		t.Fatal("was expecting error removing directory with hidden files")
+	}
+	if _, err := ms.Open("root/d/bar.txt"); !ms.IsNotExist(err) {
+		t.Fatalf("was expecting is not exist error, got %v", err)
+	}
+	if _, err := ms.Open("root/d/hidden.html"); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestStatWithGlob(t *testing.T) {
+	t.Parallel()
+	s := limitfs.New(
+		limitfs.Config{Root: "root", Recursive: true, Glob: "root/*.txt"},
+		newMemSystem(),
+	)
+	if _, err := fsutil.Stat(s, "foo.txt"); err != nil {
+		t.Fatal(err)
+	}
+	fi, err := fsutil.Stat(s, "d")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !fi.IsDir() {
+		t.Fatal("was expecting dir")
+	}
+	if _, err := fsutil.Stat(s, "d/bar.txt"); !s.IsNotExist(err) {
+		t.Fatalf("was expecting is not exist error, got %v", err)
+	}
+}
+
+func TestCapabilitiesWithGlob(t *testing.T) {
+	t.Parallel()
+	s := limitfs.New(
+		limitfs.Config{Root: "root", Recursive: true, Glob: "root/*.txt"},
+		newMemSystem(),
+	)
+	infos, err := fsutil.ReadDir(s, ".")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(infos) != 2 || infos[0].Name() != "d" || infos[1].Name() != "foo.txt" {
+		t.Fatalf("did not find expected infos, got %v", infos)
+	}
+	if _, err := fsutil.ReadFile(s, "foo.txt"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := fsutil.ReadFile(s, "d/bar.txt"); !s.IsNotExist(err) {
+		t.Fatalf("was expecting is not exist error, got %v", err)
+	}
+	if err := fsutil.Chmod(s, "foo.txt", 0600); err != nil {
+		t.Fatal(err)
+	}
+	if err := fsutil.Chmod(s, "d/bar.txt", 0600); !s.IsNotExist(err) {
+		t.Fatalf("was expecting is not exist error, got %v", err)
+	}
+}
+
+func TestRecursiveGlob(t *testing.T) {
+	t.Parallel()
+	s := limitfs.New(
+		limitfs.Config{Root: "root", Recursive: true, Glob: "**/d/*.txt"},
+		newMemSystem(),
+	)
+	if _, err := s.Open("d/bar.txt"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.Open("foo.txt"); !s.IsNotExist(err) {
+		t.Fatalf("was expecting is not exist error, got %v", err)
+	}
+	names, err := fsutil.Glob(s, "**")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(names) != 2 || names[0] != "d" || names[1] != "d/bar.txt" {
+		t.Fatalf("did not find expected names, got %v", names)
+	}
+}
+
+func TestConformance(t *testing.T) {
+	t.Parallel()
+	newSystem := func(c limitfs.Config) func(*testing.T, map[string]string) (fs.System, string) {
+		return func(t *testing.T, files map[string]string) (fs.System, string) {
+			ms := memfs.NewWithFiles(nil)
+			if err := fstest.Populate(ms, "root", files); err != nil {
+				t.Fatal(err)
+			}
+			if err := fstest.Populate(ms, "hidden", files); err != nil {
+				t.Fatal(err)
+			}
+			return limitfs.New(c, ms), "/"
+		}
+	}
+	t.Run("ReadWrite", func(t *testing.T) {
+		t.Parallel()
+		fstest.TestSystem(t, fstest.Config{
+			New: newSystem(limitfs.Config{Root: "root", Recursive: true}),
+		})
+	})
+	t.Run("ReadOnly", func(t *testing.T) {
+		t.Parallel()
+		fstest.TestSystem(t, fstest.Config{
+			New:      newSystem(limitfs.Config{Root: "root", Recursive: true, ReadOnly: true}),
+			ReadOnly: true,
+		})
+	})
+}
+
+// Uses realfs since memfs can not be used from the goroutine forwarding
+// Events.
+func TestWatch(t *testing.T) {
+	t.Parallel()
+	dir, err := ioutil.TempDir("", "limitfs_test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	r := realfs.New()
+	s := limitfs.New(limitfs.Config{Root: dir, Recursive: true, Glob: "**/*.txt"}, r)
+	w, err := fsutil.Watch(s, "/", false)
+	if errors.Is(err, fs.ErrNotSupported) {
+		t.Skip(err)
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer w.Close()
+	for _, name := range []string{"hidden.log", "foo.txt"} {
+		if err := fsutil.WriteFile(r, filepath.Join(dir, name), nil, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := r.Mkdir(filepath.Join(dir, "e"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	for _, expected := range []string{"CREATE /foo.txt", "CREATE /e"} {
+		if ev := <-w.Events(); ev.String() != expected {
+			t.Fatalf("was expecting %s, got %s", expected, ev)
+		}
+	}
+}
+
+func TestWatchNotRecursive(t *testing.T) {
+	t.Parallel()
+	m := newMemSystem()
+	s := limitfs.New(limitfs.Config{Root: "root"}, m)
+	if _, err := fsutil.Watch(s, "d/bar.txt", false); !s.IsNotExist(err) {
+		t.Fatalf("was expecting is not exist error, got %v", err)
+	}
+	w, err := fsutil.Watch(s, "", true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer w.Close()
+	for _, name := range []string{"root/d/baz.txt", "root/baz.txt"} {
+		if err := fsutil.WriteFile(m, name, nil, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if ev := <-w.Events(); ev.String() != "CREATE baz.txt" {
+		t.Fatalf("was expecting CREATE baz.txt, got %s", ev)
+	}
+}
+
+func TestXattrWithGlob(t *testing.T) {
+	t.Parallel()
+	s := limitfs.New(
+		limitfs.Config{Root: "root", Recursive: true, Glob: "root/*.txt"},
+		newMemSystem(),
+	)
+	if err := fsutil.Setxattr(s, "foo.txt", "user.hash", []byte("abc")); err != nil {
+		t.Fatal(err)
+	}
+	b, err := fsutil.Getxattr(s, "foo.txt", "user.hash")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(b) != "abc" {
+		t.Fatalf("was expecting abc, got %s", b)
+	}
+	if _, err := fsutil.Listxattr(s, "d/bar.txt"); !s.IsNotExist(err) {
+		t.Fatalf("was expecting is not exist error, got %v", err)
+	}
+	r := limitfs.New(limitfs.Config{Root: "root", ReadOnly: true}, newMemSystem())
+	if err := fsutil.Removexattr(r, "foo.txt", "user.hash"); !fsutil.IsReadOnly(err) {
+		t.Fatalf("was expecting read-only error, got %v", err)
+	}
+}
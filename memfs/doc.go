@@ -1,5 +1,13 @@
 // Package memfs provides an in-memory File System.
 //
-// Note, operations are not protected for concurrent access and locking is your
-// responsibility.
+// The System and its Files are safe for concurrent use. Files lock
+// individually, so reads and writes of different files don't block each other
+// and only changes to the names in the System are serialized.
+//
+// For tests, NewFromMap, NewFromTxtar and NewFromDir create a populated System
+// from a fixture, and DumpTxtar allows comparing a System to a golden file.
+// Snapshot and Clone cheaply copy a System, sharing the file contents until
+// they are written, and Rollback restores a System to an earlier Snapshot.
+// The timestamps set by a System come from the Clock in its Config, which can
+// be a fstest.FakeClock to keep them predictable.
 package memfs
@@ -0,0 +1,10 @@
+//go:build !plan9
+
+package memfs
+
+import "syscall"
+
+const (
+	errBadFD    = syscall.EBADF // access mode doesn't allow the operation
+	errTooLarge = syscall.EFBIG // file would grow beyond maxSize
+)
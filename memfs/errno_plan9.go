@@ -0,0 +1,9 @@
+package memfs
+
+import "errors"
+
+// Plan 9 reports errors as strings, and has no error numbers for these.
+var (
+	errBadFD    = errors.New("bad file descriptor")
+	errTooLarge = errors.New("file too large")
+)
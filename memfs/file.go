@@ -1,41 +1,66 @@
 package memfs
 
 import (
-	"errors"
 	"io"
+	"maps"
 	"os"
 	"path/filepath"
+	"sync"
+	"sync/atomic"
 	"time"
-)
 
-var (
-	errNotDir             = errors.New("memfs: file is not a directory")
-	errIsDir              = errors.New("memfs: file is a directory")
-	errAlreadyClosed      = errors.New("memfs: file already closed")
-	errTruncateOutOfRange = errors.New("memfs: file truncation out of range")
-	errSeekOutOfRange     = errors.New("memfs: file seek out of range")
-	errSeekWhenceInvalid  = errors.New("memfs: file seek whence invalid")
-	errOffsetOutOfRange   = errors.New("memfs: file offset out of range")
+	"github.com/daaku/go.fs"
 )
 
-// In-memory File representation.
+// The last inode number handed out.
+var inodes atomic.Uint64
+
+// In-memory File representation. A File is a handle with its own offset and
+// closed state, and every Open of the same name in a System returns a new File
+// sharing the data and metadata of the stored one. Files are safe for
+// concurrent use.
 type File struct {
-	name     string
+	*node
+	mu     sync.Mutex // protects closed & off, acquired before node.mu
+	flag   int        // as given to OpenFile
+	closed bool
+	off    int64 // dual purpose for buf & infos depending on isDir
+}
+
+// The bits of the flag given to OpenFile that select the access mode.
+const accessMode = os.O_RDONLY | os.O_WRONLY | os.O_RDWR
+
+// The largest size of a File. Writes and truncates beyond it fail with EFBIG,
+// instead of trying to allocate the memory for it.
+const maxSize = 1<<31 - 1
+
+// The data and metadata of a file, shared by all the Files opened for it.
+type node struct {
+	mu       sync.RWMutex
+	name     atomic.Pointer[string]
 	fileInfo *MemFileInfo
 	uid      int
 	gid      int
-	closed   bool
+	inode    uint64
 	isDir    bool
-	off      int64         // dual purpose for buf & infos depending on isDir
 	buf      []byte        // for files
+	cow      bool          // buf is shared and must be copied before changes
 	infos    []os.FileInfo // for directories
+	watch    *watchList    // set once added to a System
+	clock    fs.Clock      // set once added to a System
+	key      string        // name in the System, used for Events
+	detached atomic.Bool   // set when Rollback removes it from the System
+	lock     *lock
+	xattrs   map[string][]byte
 }
 
 // Create a new File.
 func NewFile(name string, mode os.FileMode, mtime time.Time, data []byte) *File {
-	return &File{
-		name: name,
-		buf:  data,
+	n := &node{
+		buf:   data,
+		inode: inodes.Add(1),
+		lock:  newLock(),
+		clock: fs.SystemClock,
 		fileInfo: NewFileInfo(FileInfo{
 			Name:    filepath.Base(name),
 			Size:    int64(len(data)),
@@ -43,64 +68,169 @@ func NewFile(name string, mode os.FileMode, mtime time.Time, data []byte) *File
 			ModTime: mtime,
 		}),
 	}
+	n.name.Store(&name)
+	return &File{node: n, flag: os.O_RDWR}
 }
 
 // Create a new Directory.
 func NewDir(name string, mode os.FileMode, mtime time.Time, infos []os.FileInfo) *File {
-	return &File{
+	n := &node{
 		isDir: true,
-		name:  name,
 		infos: infos,
+		inode: inodes.Add(1),
+		lock:  newLock(),
+		clock: fs.SystemClock,
 		fileInfo: NewFileInfo(FileInfo{
 			Name:    filepath.Base(name),
-			Mode:    mode,
+			Mode:    mode | os.ModeDir,
 			ModTime: mtime,
 		}),
 	}
+	n.name.Store(&name)
+	return &File{node: n, flag: os.O_RDWR}
+}
+
+// Returns a new open File for the same file, with its own offset and the
+// access mode and O_APPEND behavior from flag.
+func (f *File) open(flag int) *File {
+	return &File{node: f.node, flag: flag}
+}
+
+// Sets the System the File belongs to, and its name there.
+func (f *File) attach(s system, key string) {
+	f.node.mu.Lock()
+	defer f.node.mu.Unlock()
+	f.watch = s.watch
+	f.clock = s.clock
+	f.key = key
+}
+
+// Stops sending Events for the file once it's removed from its System. The
+// Files opened for it keep working, like they do for an unlinked file.
+func (f *File) unwatch() {
+	f.node.mu.Lock()
+	defer f.node.mu.Unlock()
+	f.watch = nil
+}
+
+// Removes the file from its System, making all the Files opened for it behave
+// as if they were closed. No further Events are sent for it.
+func (f *File) detach() {
+	f.unwatch()
+	f.detached.Store(true)
+}
+
+// Returns a new File for a copy of the file, which shares the contents with
+// the original until either of them changes. The copy keeps the Inode, but
+// isn't attached to a System.
+func (f *File) clone() *File {
+	f.node.mu.Lock()
+	defer f.node.mu.Unlock()
+	f.cow = true
+	n := &node{
+		fileInfo: f.fileInfo.clone(),
+		uid:      f.uid,
+		gid:      f.gid,
+		inode:    f.inode,
+		isDir:    f.isDir,
+		buf:      f.buf,
+		cow:      true,
+		infos:    append([]os.FileInfo(nil), f.infos...),
+		lock:     newLock(),
+		clock:    f.clock,
+		xattrs:   maps.Clone(f.xattrs),
+	}
+	n.name.Store(f.name.Load())
+	return &File{node: n, flag: os.O_RDWR}
 }
 
-// Chmod changes the mode of the file to mode.
+// Chmod changes the mode of the file to mode. The type bits are preserved.
 func (f *File) Chmod(mode os.FileMode) error {
-	f.fileInfo.SetMode(mode)
+	f.node.mu.Lock()
+	defer f.node.mu.Unlock()
+	f.fileInfo.SetMode(mode&^os.ModeType | f.fileInfo.Mode()&os.ModeType)
+	f.changed(fs.OpChmod)
 	return nil
 }
 
 // Chown changes the numeric uid and gid of the named file.
 func (f *File) Chown(uid, gid int) error {
+	f.node.mu.Lock()
+	defer f.node.mu.Unlock()
 	f.uid = uid
 	f.gid = gid
+	f.changed(fs.OpChmod)
 	return nil
 }
 
-// Get the owner UID.
-func (f *File) OwnerUID() (int, error) {
-	return f.uid, nil
+// Chtimes changes the access and modification times of the file.
+func (f *File) Chtimes(atime time.Time, mtime time.Time) error {
+	f.node.mu.Lock()
+	defer f.node.mu.Unlock()
+	f.fileInfo.SetAccessTime(atime)
+	f.fileInfo.SetModTime(mtime)
+	f.changed(fs.OpChmod)
+	return nil
 }
 
-// Get the owner GID.
-func (f *File) OwnerGID() (int, error) {
-	return f.gid, nil
+// Times returns the timestamps of the file.
+func (f *File) Times() (fs.Times, error) {
+	return f.fileInfo.Times(), nil
+}
+
+// Ident returns the ownership and identity of the file. Every File has a
+// unique Inode, and directories have a link for each subdirectory.
+func (f *File) Ident() (fs.Ident, error) {
+	f.node.mu.RLock()
+	defer f.node.mu.RUnlock()
+	nlink := uint64(1)
+	if f.isDir {
+		nlink = 2
+		for _, fi := range f.infos {
+			if fi.IsDir() {
+				nlink++
+			}
+		}
+	}
+	return fs.Ident{UID: f.uid, GID: f.gid, Inode: f.inode, Nlink: nlink}, nil
+}
+
+// Returns true if the directory has no entries.
+func (f *File) empty() bool {
+	f.node.mu.RLock()
+	defer f.node.mu.RUnlock()
+	return len(f.infos) == 0
 }
 
 // Close closes the File, rendering it unusable for I/O.
 func (f *File) Close() error {
+	f.mu.Lock()
 	f.closed = true
+	f.mu.Unlock()
+	f.lock.release(f)
 	return nil
 }
 
-// Check if the File has been closed.
+// Check if the File has been closed, or detached from its System by Rollback.
 func (f *File) IsClosed() bool {
-	return f.closed
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.isClosed()
+}
+
+// Like IsClosed, but must be called with the File locked.
+func (f *File) isClosed() bool {
+	return f.closed || f.detached.Load()
 }
 
 // Name returns the name of the file as presented to Open.
 func (f *File) Name() string {
-	return f.name
+	return *f.name.Load()
 }
 
 // Name returns the name of the file as presented to Open.
 func (f *File) SetName(name string) {
-	f.name = name
+	f.name.Store(&name)
 	f.fileInfo.SetName(filepath.Base(name))
 }
 
@@ -108,69 +238,116 @@ func (f *File) SetName(name string) {
 // read and an error, if any. EOF is signaled by a zero count with err set to
 // io.EOF.
 func (f *File) Read(b []byte) (n int, err error) {
-	if f.IsClosed() {
-		return 0, errAlreadyClosed
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if len(b) == 0 && !f.isClosed() && !f.isDir {
+		return 0, nil
 	}
-
-	if f.isDir {
-		return 0, errIsDir
+	if err := f.check("read", false); err != nil {
+		return 0, err
+	}
+	n, err = f.readAt(b, f.off)
+	f.off += int64(n)
+	if n > 0 && err == io.EOF {
+		err = nil
 	}
+	return n, err
+}
 
-	if f.off >= int64(len(f.buf)) {
+// Reads from off without changing the offset, returning io.EOF if less than
+// len(b) bytes were read.
+func (f *File) readAt(b []byte, off int64) (n int, err error) {
+	f.node.mu.RLock()
+	defer f.node.mu.RUnlock()
+	if off >= int64(len(f.buf)) {
 		if len(b) == 0 {
-			return
+			return 0, nil
 		}
 		return 0, io.EOF
 	}
-	n = copy(b, f.buf[f.off:])
-	f.off += int64(n)
-	return
+	n = copy(b, f.buf[off:])
+	if n < len(b) {
+		err = io.EOF
+	}
+	return n, err
 }
 
 // ReadAt reads len(b) bytes from the File starting at byte offset off. It
 // returns the number of bytes read and the error, if any. ReadAt always
 // returns a non-nil error when n < len(b). At end of file, that error is
-// io.EOF.
+// io.EOF. It does not change the offset.
 func (f *File) ReadAt(b []byte, off int64) (n int, err error) {
-	if f.IsClosed() {
-		return 0, errAlreadyClosed
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.isClosed() {
+		return 0, f.pathError("read", fs.ErrClosed)
 	}
 
 	if f.isDir {
-		return 0, errIsDir
+		return 0, f.pathError("read", fs.ErrIsDir)
 	}
 
-	f.off = off
-	return f.Read(b)
+	if off < 0 {
+		return 0, f.pathError("read", fs.ErrInvalid)
+	}
+	if len(b) == 0 {
+		return 0, nil
+	}
+	if err := f.check("read", false); err != nil {
+		return 0, err
+	}
+	return f.readAt(b, off)
 }
 
-// Returns the FileInfos of the files in the directory.
+// Returns the FileInfos of the files in the directory, continuing from where
+// the previous call left off. If n > 0 at most n FileInfos are returned, and
+// io.EOF once there are none left. Otherwise all the remaining FileInfos are
+// returned.
 func (f *File) Readdir(n int) (infos []os.FileInfo, err error) {
 	if !f.isDir {
-		return nil, errNotDir
+		return nil, f.pathError("readdir", fs.ErrNotDir)
 	}
 
-	if n <= 0 {
-		f.off = 0
-		return f.infos, nil
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.isClosed() {
+		return nil, f.pathError("readdir", fs.ErrClosed)
 	}
-
-	m := f.off + int64(n)
+	f.node.mu.RLock()
+	defer f.node.mu.RUnlock()
+	// the listing may have shrunk since the last call
 	l := int64(len(f.infos))
-	if m >= l {
-		err = io.EOF
-		m = l
+	start, end := min(f.off, l), l
+	if n > 0 {
+		if start == l {
+			f.off = l
+			return nil, io.EOF
+		}
+		end = min(start+int64(n), l)
 	}
 
-	infos = f.infos[f.off:m]
-	f.off = m
-	return
+	infos = copyInfos(f.infos[start:end])
+	f.off = end
+	return infos, nil
+}
+
+// Returns copies of the FileInfos in a directory listing, so they don't change
+// along with the files.
+func copyInfos(infos []os.FileInfo) []os.FileInfo {
+	c := make([]os.FileInfo, len(infos))
+	for i, fi := range infos {
+		if mfi, ok := fi.(*MemFileInfo); ok {
+			fi = mfi.clone()
+		}
+		c[i] = fi
+	}
+	return c
 }
 
 // Returns names of files in the directory.
 func (f *File) Readdirnames(n int) (names []string, err error) {
 	if !f.isDir {
-		return nil, errNotDir
+		return nil, f.pathError("readdir", fs.ErrNotDir)
 	}
 
 	fis, err := f.Readdir(n)
@@ -184,168 +361,364 @@ func (f *File) Readdirnames(n int) (names []string, err error) {
 // Reset infos for a directory.
 func (f *File) SetDirInfos(infos []os.FileInfo) error {
 	if !f.isDir {
-		return errNotDir
+		return f.pathError("readdir", fs.ErrNotDir)
 	}
 
+	f.node.mu.Lock()
 	f.infos = infos
+	f.node.mu.Unlock()
 	f.Reset()
 	return nil
 }
 
-// Add a new info to the directory. Will also reset the internal offset.
+// Add a new info to the directory. Will also reset the internal offset. A
+// System maintains the listings of its directories, so this is only needed
+// when building the Files given to NewSystem.
 func (f *File) AddDirInfo(info os.FileInfo) error {
 	if !f.isDir {
-		return errNotDir
+		return f.pathError("readdir", fs.ErrNotDir)
 	}
 
+	f.node.mu.Lock()
 	f.infos = append(f.infos, info)
+	f.node.mu.Unlock()
+	f.Reset()
+	return nil
+}
+
+// Remove the named info from the directory. Will also reset the internal
+// offset.
+func (f *File) RemoveDirInfo(name string) error {
+	if !f.isDir {
+		return f.pathError("readdir", fs.ErrNotDir)
+	}
+
+	f.node.mu.Lock()
+	for ix, fi := range f.infos {
+		if fi.Name() == name {
+			f.infos = append(f.infos[:ix:ix], f.infos[ix+1:]...)
+			break
+		}
+	}
+	f.node.mu.Unlock()
 	f.Reset()
 	return nil
 }
 
 // Reset offset for Read/Write/Readdir/Readdirnames.
 func (f *File) Reset() {
+	f.mu.Lock()
+	defer f.mu.Unlock()
 	f.off = 0
 }
 
 // Seek sets the offset for the next Read or Write on file to offset,
 // interpreted according to whence: 0 means relative to the origin of the file,
 // 1 means relative to the current offset, and 2 means relative to the end. It
-// returns the new offset and an error, if any.
+// returns the new offset and an error, if any. Seeking past the end is
+// allowed, and a following Write will leave a hole filled with zeros.
 func (f *File) Seek(offset int64, whence int) (ret int64, err error) {
-	if f.IsClosed() {
-		return 0, errAlreadyClosed
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.isClosed() {
+		return 0, f.pathError("seek", fs.ErrClosed)
 	}
 
 	if f.isDir {
-		return 0, errIsDir
+		return 0, f.pathError("seek", fs.ErrIsDir)
 	}
 
-	l := int64(len(f.buf))
 	switch whence {
-	case os.SEEK_SET:
+	case io.SeekStart:
 		ret = offset
-	case os.SEEK_CUR:
+	case io.SeekCurrent:
 		ret = f.off + offset
-	case os.SEEK_END:
-		ret = l - offset
+	case io.SeekEnd:
+		f.node.mu.RLock()
+		ret = int64(len(f.buf)) + offset
+		f.node.mu.RUnlock()
 	default:
-		return f.off, errSeekWhenceInvalid
+		return 0, f.pathError("seek", fs.ErrInvalid)
 	}
 
-	if ret > l || ret < 0 {
-		return f.off, errSeekOutOfRange
+	if ret < 0 {
+		return 0, f.pathError("seek", fs.ErrInvalid)
 	}
 	f.off = ret
 	return ret, nil
 }
 
-// Stat returns the FileInfo structure describing this File.
+// Stat returns the FileInfo structure describing this File. Like os.File, it
+// is a snapshot that does not reflect later changes.
 func (f *File) Stat() (fi os.FileInfo, err error) {
 	if f.IsClosed() {
-		return nil, errAlreadyClosed
+		return nil, f.pathError("stat", fs.ErrClosed)
 	}
+	return f.info(), nil
+}
 
-	return f.fileInfo, nil
+// Returns a copy of the FileInfo, consistent with the contents.
+func (f *File) info() *MemFileInfo {
+	f.node.mu.RLock()
+	defer f.node.mu.RUnlock()
+	return f.fileInfo.clone()
 }
 
 // For in memory files Sync does nothing.
 func (f *File) Sync() (err error) {
 	if f.IsClosed() {
-		return errAlreadyClosed
+		return f.pathError("sync", fs.ErrClosed)
 	}
 
 	return nil
 }
 
 // Truncate changes the size of the file. It does not change the I/O offset.
+// Growing the file fills it with zeros.
 func (f *File) Truncate(size int64) error {
-	if f.IsClosed() {
-		return errAlreadyClosed
+	f.mu.Lock()
+	closed, flag := f.isClosed(), f.flag
+	f.mu.Unlock()
+	if closed {
+		return f.pathError("truncate", fs.ErrClosed)
 	}
+	if flag&accessMode == os.O_RDONLY {
+		return f.pathError("truncate", fs.ErrInvalid)
+	}
+	return f.truncate(size)
+}
 
+// Truncate without requiring the File to be open, used by the System.
+func (f *File) truncate(size int64) error {
 	if f.isDir {
-		return errIsDir
+		return f.pathError("truncate", fs.ErrIsDir)
 	}
 
-	if size < 0 || size > int64(len(f.buf)) {
-		return errTruncateOutOfRange
+	if size < 0 {
+		return f.pathError("truncate", fs.ErrInvalid)
+	}
+	if size > maxSize {
+		return f.pathError("truncate", errTooLarge)
+	}
+	f.node.mu.Lock()
+	defer f.node.mu.Unlock()
+	l := int64(len(f.buf))
+	if size == l {
+		return nil
+	}
+	f.own()
+	switch {
+	case size > l:
+		f.grow(l, int(size-l))
+	default:
+		f.buf = f.buf[0:size]
 	}
-	f.buf = f.buf[0:size]
 	f.updateFileInfoSize()
+	f.changed(fs.OpWrite)
 	return nil
 }
 
 // Write writes len(b) bytes to the File. It returns the number of bytes
-// written and an error, if any.
+// written and an error, if any. Files opened with os.O_APPEND always write at
+// the end.
 func (f *File) Write(b []byte) (ret int, err error) {
-	if f.IsClosed() {
-		return 0, errAlreadyClosed
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if err := f.check("write", true); err != nil {
+		return 0, err
 	}
 
-	if f.isDir {
-		return 0, errIsDir
+	f.node.mu.Lock()
+	defer f.node.mu.Unlock()
+	if f.flag&os.O_APPEND != 0 && len(b) > 0 {
+		f.off = int64(len(f.buf))
 	}
-
-	f.grow(len(b))
-	ret = copy(f.buf[f.off:], b)
+	ret, err = f.writeAt(b, f.off)
 	f.off += int64(ret)
-	f.updateFileInfoSize()
-	return ret, nil
+	return ret, err
 }
 
 // WriteAt writes len(b) bytes to the File starting at byte offset off. It
 // returns the number of bytes written and an error, if any. WriteAt returns a
-// non-nil error when n != len(b).
+// non-nil error when n != len(b). Writing past the end leaves a hole filled
+// with zeros.
 func (f *File) WriteAt(b []byte, off int64) (ret int, err error) {
-	if f.IsClosed() {
-		return 0, errAlreadyClosed
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.isClosed() {
+		return 0, f.pathError("write", fs.ErrClosed)
 	}
 
 	if f.isDir {
-		return 0, errIsDir
+		return 0, f.pathError("write", fs.ErrIsDir)
+	}
+
+	if off < 0 || f.flag&os.O_APPEND != 0 {
+		return 0, f.pathError("write", fs.ErrInvalid)
 	}
+	if len(b) == 0 {
+		return 0, nil
+	}
+	if err := f.check("write", true); err != nil {
+		return 0, err
+	}
+	f.node.mu.Lock()
+	defer f.node.mu.Unlock()
+	return f.writeAt(b, off)
+}
 
-	if off > int64(len(f.buf)) {
-		return 0, errOffsetOutOfRange
+// Writes at off without changing the offset. Must be called with the node
+// locked.
+func (f *File) writeAt(b []byte, off int64) (int, error) {
+	if len(b) == 0 {
+		return 0, nil
+	}
+	if off > maxSize-int64(len(b)) {
+		return 0, f.pathError("write", errTooLarge)
 	}
-	f.grow(len(b))
-	ret = copy(f.buf[off:], b)
+	f.own()
+	f.grow(off, len(b))
+	n := copy(f.buf[off:], b)
 	f.updateFileInfoSize()
-	return ret, nil
+	f.changed(fs.OpWrite)
+	return n, nil
 }
 
 // WriteString is like Write, but writes the contents of string s rather than
 // an array of bytes.
 func (f *File) WriteString(s string) (ret int, err error) {
-	if f.IsClosed() {
-		return 0, errAlreadyClosed
+	return f.Write([]byte(s))
+}
+
+// ReadFrom writes the data read from r until EOF to the File.
+func (f *File) ReadFrom(r io.Reader) (n int64, err error) {
+	// check up front, so nothing is read from r if it can't be written
+	f.mu.Lock()
+	err = f.check("write", true)
+	f.mu.Unlock()
+	if err != nil {
+		return 0, err
 	}
 
-	if f.isDir {
-		return 0, errIsDir
+	buf := make([]byte, 32*1024)
+	for {
+		nr, errR := r.Read(buf)
+		if nr > 0 {
+			nw, errW := f.Write(buf[:nr])
+			n += int64(nw)
+			if errW != nil {
+				return n, errW
+			}
+		}
+		if errR == io.EOF {
+			return n, nil
+		}
+		if errR != nil {
+			return n, errR
+		}
 	}
+}
 
-	f.grow(len(s))
-	ret = copy(f.buf[f.off:], s)
-	f.off += int64(ret)
-	f.updateFileInfoSize()
-	return ret, nil
+// WriteTo writes the remaining contents of the File to w in a single Write.
+func (f *File) WriteTo(w io.Writer) (n int64, err error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if err := f.check("read", false); err != nil {
+		return 0, err
+	}
+
+	// copied so w is called without holding the lock, since it may be another
+	// File for the same file
+	f.node.mu.RLock()
+	var rest []byte
+	if f.off < int64(len(f.buf)) {
+		rest = append(rest, f.buf[f.off:]...)
+	}
+	f.node.mu.RUnlock()
+	if len(rest) == 0 {
+		return 0, nil
+	}
+	nw, err := w.Write(rest)
+	f.off += int64(nw)
+	return int64(nw), err
 }
 
-// Grows the buffer to guarantee space for n more bytes. Note, this modifies
-// the length of the buffer in addition to the capacity to allow for a simple
-// copy operation to follow.
-func (f *File) grow(n int) {
+// Grows the buffer to guarantee space for n bytes at off, filling any space
+// between the current end and off with zeros. Note, this modifies the length
+// of the buffer in addition to the capacity to allow for a simple copy
+// operation to follow. Must be called with the node locked.
+func (f *File) grow(off int64, n int) {
 	l := len(f.buf)
-	c := cap(f.buf)
-	if l+n > c {
-		buf := make([]byte, (2*c)+n)
+	end := int(off) + n
+	if end <= l {
+		return
+	}
+	if c := cap(f.buf); end > c {
+		buf := make([]byte, end, max((2*c)+n, end))
 		copy(buf, f.buf)
-		f.buf = buf[:l+n]
+		f.buf = buf
 	} else {
-		f.buf = f.buf[:l+n]
+		// the space may hold data from before a truncate
+		f.buf = f.buf[:end]
+		clear(f.buf[l:])
+	}
+}
+
+// Makes a private copy of a shared buffer before it is changed. Must be called
+// with the node locked.
+func (f *File) own() {
+	if f.cow {
+		f.buf = append([]byte(nil), f.buf...)
+		f.cow = false
+	}
+}
+
+// Checks the File is open and its access mode allows the op. Must be called
+// with the File locked.
+func (f *File) check(op string, write bool) error {
+	if f.isClosed() {
+		return f.pathError(op, fs.ErrClosed)
+	}
+
+	if f.isDir {
+		return f.pathError(op, fs.ErrIsDir)
 	}
+
+	mode := f.flag & accessMode
+	if (write && mode == os.O_RDONLY) || (!write && mode == os.O_WRONLY) {
+		return f.pathError(op, errBadFD)
+	}
+	return nil
+}
+
+// Returns a *fs.PathError for this File.
+func (f *File) pathError(op string, err error) error {
+	return &fs.PathError{Op: op, Path: f.Name(), Err: err}
+}
+
+// Updates the timestamps and notifies the Watchers of the System about a
+// change. Writes update the modification time, and all changes update the
+// change time. Reads do not update the access time. Must be called with the
+// node locked.
+func (f *File) changed(op fs.Op) {
+	now := f.clock.Now()
+	if op&fs.OpWrite != 0 {
+		f.fileInfo.SetModTime(now)
+	}
+	f.fileInfo.SetChangeTime(now)
+	if f.watch != nil {
+		f.watch.notify(f.key, op)
+	}
+}
+
+// Updates the timestamps of a directory after its entries changed. Watchers
+// are not notified, since the entries report their own Events.
+func (f *File) entriesChanged() {
+	f.node.mu.Lock()
+	defer f.node.mu.Unlock()
+	now := f.clock.Now()
+	f.fileInfo.SetModTime(now)
+	f.fileInfo.SetChangeTime(now)
 }
 
 // Updates the size in the underlying FileInfo.
@@ -2,13 +2,18 @@ package memfs_test
 
 import (
 	"bytes"
+	"errors"
 	"io"
 	"io/ioutil"
+	"math"
 	"os"
-	"strings"
+	"sync"
+	"syscall"
 	"testing"
 	"time"
 
+	"github.com/daaku/go.fs"
+	"github.com/daaku/go.fs/fsutil"
 	"github.com/daaku/go.fs/memfs"
 )
 
@@ -41,22 +46,22 @@ func TestFileChmod(t *testing.T) {
 	}
 }
 
-func TestFileDefaultOwner(t *testing.T) {
+func TestFileDefaultIdent(t *testing.T) {
 	t.Parallel()
 	f := memfs.NewFile("foo", dMode, dTime, nil)
-	gid, err := f.OwnerGID()
+	ident, err := f.Ident()
 	if err != nil {
 		t.Fatal(err)
 	}
-	if gid != 0 {
-		t.Fatal("expected 0 gid")
+	if ident.UID != 0 || ident.GID != 0 || ident.Nlink != 1 || ident.Inode == 0 {
+		t.Fatalf("did not find expected ident, got %+v", ident)
 	}
-	uid, err := f.OwnerUID()
+	other, err := memfs.NewFile("foo", dMode, dTime, nil).Ident()
 	if err != nil {
 		t.Fatal(err)
 	}
-	if uid != 0 {
-		t.Fatal("expected 0 uid")
+	if other.Inode == ident.Inode {
+		t.Fatal("was expecting unique inodes")
 	}
 }
 
@@ -68,19 +73,30 @@ func TestFileChown(t *testing.T) {
 	if err != nil {
 		t.Fatal(err)
 	}
-	actualGID, err := f.OwnerGID()
+	ident, err := f.Ident()
 	if err != nil {
 		t.Fatal(err)
 	}
-	if actualGID != expectedGID {
+	if ident.GID != expectedGID {
 		t.Fatal("did not find expected gid")
 	}
-	actualUID, err := f.OwnerUID()
+	if ident.UID != expectedUID {
+		t.Fatal("did not find expected uid")
+	}
+}
+
+func TestDirIdent(t *testing.T) {
+	t.Parallel()
+	d := memfs.NewDir("d", dMode, dTime, []os.FileInfo{
+		memfs.NewFileInfo(memfs.FileInfo{Name: "a", Mode: os.ModeDir}),
+		memfs.NewFileInfo(memfs.FileInfo{Name: "b"}),
+	})
+	ident, err := d.Ident()
 	if err != nil {
 		t.Fatal(err)
 	}
-	if actualUID != expectedUID {
-		t.Fatal("did not find expected uid")
+	if ident.Nlink != 3 {
+		t.Fatalf("was expecting 3 links, got %d", ident.Nlink)
 	}
 }
 
@@ -204,8 +220,8 @@ func TestFileReadAt(t *testing.T) {
 	f := memfs.NewFile("foo", dMode, dTime, []byte("ab"))
 	b := make([]byte, 10)
 	n, err := f.ReadAt(b, 1)
-	if err != nil {
-		t.Fatal(err)
+	if err != io.EOF {
+		t.Fatalf("was expecting EOF for a short read, got %v", err)
 	}
 	if n != 1 {
 		t.Fatal("did not find expected count")
@@ -213,6 +229,16 @@ func TestFileReadAt(t *testing.T) {
 	if b[0] != 'b' {
 		t.Fatal("did not find expected byte")
 	}
+	if _, err := f.ReadAt(b, -1); !errors.Is(err, fs.ErrInvalid) {
+		t.Fatalf("was expecting ErrInvalid, got %v", err)
+	}
+	out, err := ioutil.ReadAll(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(out) != "ab" {
+		t.Fatalf("was expecting the offset to be untouched, got %s", out)
+	}
 }
 
 func TestFileSeek(t *testing.T) {
@@ -232,7 +258,7 @@ func TestFileSeek(t *testing.T) {
 	if n != 2 {
 		t.Fatalf("was expecting 2 got %d", n)
 	}
-	n, err = f.Seek(7, os.SEEK_END)
+	n, err = f.Seek(-7, os.SEEK_END)
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -246,16 +272,22 @@ func TestFileSeek(t *testing.T) {
 	if n != 9 {
 		t.Fatalf("was expecting 9 got %d", n)
 	}
-	_, err = f.Seek(1, os.SEEK_CUR)
-	if err == nil || !strings.Contains(err.Error(), "out of range") {
-		t.Fatalf("was expecting out of range error: %s", err)
+	n, err = f.Seek(1, os.SEEK_END)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n != 10 {
+		t.Fatalf("was expecting seeking past the end to work, got %d", n)
+	}
+	if _, err := f.Read(make([]byte, 1)); err != io.EOF {
+		t.Fatalf("was expecting EOF, got %v", err)
 	}
 	_, err = f.Seek(-1, os.SEEK_SET)
-	if err == nil || !strings.Contains(err.Error(), "out of range") {
+	if err == nil || !errors.Is(err, fs.ErrInvalid) {
 		t.Fatalf("was expecting out of range error: %s", err)
 	}
 	_, err = f.Seek(-1, 99)
-	if err == nil || !strings.Contains(err.Error(), "whence invalid") {
+	if err == nil || !errors.Is(err, fs.ErrInvalid) {
 		t.Fatalf("was expecting whence error: %s", err)
 	}
 }
@@ -300,10 +332,7 @@ func TestFileTruncate(t *testing.T) {
 	if stat.Size() != 0 {
 		t.Fatal("did not find expected size in FileInfo")
 	}
-	if !strings.Contains(f.Truncate(1).Error(), "out of range") {
-		t.Fatal("was expecting out of range")
-	}
-	if !strings.Contains(f.Truncate(-42).Error(), "out of range") {
+	if !errors.Is(f.Truncate(-42), fs.ErrInvalid) {
 		t.Fatal("was expecting out of range")
 	}
 }
@@ -357,12 +386,66 @@ func TestFileWriteAt(t *testing.T) {
 	}
 }
 
-func TestFileWriteAtOutOfRange(t *testing.T) {
+func TestFileWriteAtHole(t *testing.T) {
 	t.Parallel()
 	f := memfs.NewFile("foo", dMode, dTime, nil)
-	_, err := f.WriteAt([]byte("a"), 1)
-	if err == nil || !strings.Contains(err.Error(), "out of range") {
-		t.Fatalf("was expecting already closed, got %s", err)
+	if _, err := f.WriteAt([]byte("a"), 2); err != nil {
+		t.Fatal(err)
+	}
+	out, err := ioutil.ReadAll(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(out) != "\x00\x00a" {
+		t.Fatalf("was expecting a hole filled with zeros, got %q", out)
+	}
+	if _, err := f.WriteAt([]byte("a"), -1); !errors.Is(err, fs.ErrInvalid) {
+		t.Fatalf("was expecting ErrInvalid, got %v", err)
+	}
+}
+
+func TestFileTruncateGrowZeroes(t *testing.T) {
+	t.Parallel()
+	f := memfs.NewFile("foo", dMode, dTime, []byte("123456789"))
+	if err := f.Truncate(2); err != nil {
+		t.Fatal(err)
+	}
+	if err := f.Truncate(4); err != nil {
+		t.Fatal(err)
+	}
+	out, err := ioutil.ReadAll(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(out) != "12\x00\x00" {
+		t.Fatalf("was expecting the old data to be cleared, got %q", out)
+	}
+}
+
+func TestFileTooLarge(t *testing.T) {
+	t.Parallel()
+	f := memfs.NewFile("foo", dMode, dTime, []byte("foo"))
+	assertTooLarge := func(err error) {
+		if !errors.Is(err, syscall.EFBIG) {
+			t.Fatalf("was expecting EFBIG, got %v", err)
+		}
+	}
+	_, err := f.WriteAt([]byte("a"), 1<<62)
+	assertTooLarge(err)
+	_, err = f.WriteAt([]byte("a"), math.MaxInt64)
+	assertTooLarge(err)
+	assertTooLarge(f.Truncate(1 << 62))
+	if _, err := f.Seek(1<<62, io.SeekStart); err != nil {
+		t.Fatal(err)
+	}
+	_, err = f.Write([]byte("a"))
+	assertTooLarge(err)
+	stat, err := f.Stat()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stat.Size() != 3 {
+		t.Fatalf("was expecting the size to be unchanged, got %d", stat.Size())
 	}
 }
 
@@ -390,7 +473,7 @@ func TestFileClosed(t *testing.T) {
 	t.Parallel()
 	f := memfs.NewFile("foo", dMode, dTime, nil)
 	assertClosed := func(err error) {
-		if !strings.Contains(err.Error(), "already closed") {
+		if !errors.Is(err, fs.ErrClosed) {
 			t.Fatalf("was expecting already closed, got %s", err)
 		}
 	}
@@ -429,7 +512,7 @@ func TestFileWithDirOperations(t *testing.T) {
 	t.Parallel()
 	f := memfs.NewFile("foo", dMode, dTime, nil)
 	assertNotDir := func(err error) {
-		if !strings.Contains(err.Error(), "is not a directory") {
+		if !errors.Is(err, fs.ErrNotDir) {
 			t.Fatalf("was expecting is not a directory, got %s", err)
 		}
 	}
@@ -447,7 +530,7 @@ func TestDirWithFileOperations(t *testing.T) {
 	t.Parallel()
 	d := memfs.NewDir("foo", dMode, dTime, nil)
 	assertIsDir := func(err error) {
-		if !strings.Contains(err.Error(), "is a directory") {
+		if !errors.Is(err, fs.ErrIsDir) {
 			t.Fatalf("was expecting is a directory, got %s", err)
 		}
 	}
@@ -483,42 +566,51 @@ func TestDirReaddir(t *testing.T) {
 		memfs.NewFileInfo(i2),
 		memfs.NewFileInfo(i3),
 	})
-	all, err := d.Readdir(0)
-	if err != nil {
-		t.Fatal(err)
-	}
-	if len(all) != 3 {
-		t.Fatal("was expecting 3")
-	}
 	some, err := d.Readdir(1)
 	if err != nil {
 		t.Fatal(err)
 	}
 	if len(some) != 1 {
-		t.Fatal("was expecting 1")
+		t.Fatalf("was expecting 1, got %v", some)
 	}
 	if actual := some[0].Name(); actual != i1.Name {
-		t.Fatal("was expecting %s but got %s", i1.Name, actual)
+		t.Fatalf("was expecting %s but got %s", i1.Name, actual)
 	}
-	some, err = d.Readdir(1)
+	rest, err := d.Readdir(0)
 	if err != nil {
 		t.Fatal(err)
 	}
-	if len(some) != 1 {
-		t.Fatalf("was expecting 1, got %v", some)
+	if len(rest) != 2 {
+		t.Fatalf("was expecting 2, got %v", rest)
 	}
-	if actual := some[0].Name(); actual != i2.Name {
-		t.Fatalf("was expecting %s but got %s", i2.Name, actual)
+	if actual := rest[1].Name(); actual != i3.Name {
+		t.Fatalf("was expecting %s but got %s", i3.Name, actual)
 	}
 	some, err = d.Readdir(1)
 	if err != io.EOF {
-		t.Fatalf("was expecting io.EOF got %s", err)
+		t.Fatalf("was expecting io.EOF got %v", err)
 	}
-	if len(some) != 1 {
-		t.Fatalf("was expecting 1, got %v", some)
+	if len(some) != 0 {
+		t.Fatalf("was expecting 0, got %v", some)
 	}
-	if actual := some[0].Name(); actual != i3.Name {
-		t.Fatalf("was expecting %s but got %s", i3.Name, actual)
+	rest, err = d.Readdir(0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(rest) != 0 {
+		t.Fatalf("was expecting 0, got %v", rest)
+	}
+	d.Reset()
+	all, err := d.Readdir(-1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(all) != 3 {
+		t.Fatalf("was expecting 3, got %v", all)
+	}
+	d.Close()
+	if _, err := d.Readdir(0); !errors.Is(err, fs.ErrClosed) {
+		t.Fatalf("was expecting closed error, got %v", err)
 	}
 }
 
@@ -538,42 +630,51 @@ func TestDirReaddirnames(t *testing.T) {
 		memfs.NewFileInfo(i2),
 		memfs.NewFileInfo(i3),
 	})
-	all, err := d.Readdirnames(0)
-	if err != nil {
-		t.Fatal(err)
-	}
-	if len(all) != 3 {
-		t.Fatal("was expecting 3")
-	}
 	some, err := d.Readdirnames(1)
 	if err != nil {
 		t.Fatal(err)
 	}
 	if len(some) != 1 {
-		t.Fatal("was expecting 1")
+		t.Fatalf("was expecting 1, got %v", some)
 	}
 	if actual := some[0]; actual != i1.Name {
-		t.Fatal("was expecting %s but got %s", i1.Name, actual)
+		t.Fatalf("was expecting %s but got %s", i1.Name, actual)
 	}
-	some, err = d.Readdirnames(1)
+	rest, err := d.Readdirnames(0)
 	if err != nil {
 		t.Fatal(err)
 	}
-	if len(some) != 1 {
-		t.Fatalf("was expecting 1, got %v", some)
+	if len(rest) != 2 {
+		t.Fatalf("was expecting 2, got %v", rest)
 	}
-	if actual := some[0]; actual != i2.Name {
-		t.Fatalf("was expecting %s but got %s", i2.Name, actual)
+	if actual := rest[1]; actual != i3.Name {
+		t.Fatalf("was expecting %s but got %s", i3.Name, actual)
 	}
 	some, err = d.Readdirnames(1)
 	if err != io.EOF {
-		t.Fatalf("was expecting io.EOF got %s", err)
+		t.Fatalf("was expecting io.EOF got %v", err)
 	}
-	if len(some) != 1 {
-		t.Fatalf("was expecting 1, got %v", some)
+	if len(some) != 0 {
+		t.Fatalf("was expecting 0, got %v", some)
 	}
-	if actual := some[0]; actual != i3.Name {
-		t.Fatalf("was expecting %s but got %s", i3.Name, actual)
+	rest, err = d.Readdirnames(0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(rest) != 0 {
+		t.Fatalf("was expecting 0, got %v", rest)
+	}
+	d.Reset()
+	all, err := d.Readdirnames(-1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(all) != 3 {
+		t.Fatalf("was expecting 3, got %v", all)
+	}
+	d.Close()
+	if _, err := d.Readdirnames(0); !errors.Is(err, fs.ErrClosed) {
+		t.Fatalf("was expecting closed error, got %v", err)
 	}
 }
 
@@ -588,7 +689,7 @@ func TestDirSetDirInfos(t *testing.T) {
 	d := memfs.NewDir("foo", dMode, dTime, []os.FileInfo{memfs.NewFileInfo(i1)})
 	some, _ := d.Readdirnames(1)
 	if actual := some[0]; actual != i1.Name {
-		t.Fatal("was expecting %s but got %s", i1.Name, actual)
+		t.Fatalf("was expecting %s but got %s", i1.Name, actual)
 	}
 	err := d.SetDirInfos([]os.FileInfo{memfs.NewFileInfo(i2)})
 	if err != nil {
@@ -611,7 +712,7 @@ func TestDirAddDirInfo(t *testing.T) {
 	d := memfs.NewDir("foo", dMode, dTime, []os.FileInfo{memfs.NewFileInfo(i1)})
 	some, _ := d.Readdirnames(1)
 	if actual := some[0]; actual != i1.Name {
-		t.Fatal("was expecting %s but got %s", i1.Name, actual)
+		t.Fatalf("was expecting %s but got %s", i1.Name, actual)
 	}
 	err := d.AddDirInfo(memfs.NewFileInfo(i2))
 	if err != nil {
@@ -622,3 +723,262 @@ func TestDirAddDirInfo(t *testing.T) {
 		t.Fatalf("was expecting %s but got %s", i2.Name, actual)
 	}
 }
+
+func TestDirChmodKeepsType(t *testing.T) {
+	t.Parallel()
+	d := memfs.NewDir("foo", 0755, dTime, nil)
+	if err := d.Chmod(0700); err != nil {
+		t.Fatal(err)
+	}
+	stat, err := d.Stat()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !stat.IsDir() || stat.Mode().Perm() != 0700 {
+		t.Fatalf("did not find expected mode, got %v", stat.Mode())
+	}
+}
+
+func TestFileLock(t *testing.T) {
+	t.Parallel()
+	f := memfs.NewFile("foo", dMode, dTime, nil)
+	if err := f.Lock(); err != nil {
+		t.Fatal(err)
+	}
+	// the owner can lock again, and convert to a shared lock
+	locked, err := f.TryLock()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !locked {
+		t.Fatal("was expecting to acquire the lock")
+	}
+	if err := f.RLock(); err != nil {
+		t.Fatal(err)
+	}
+	if err := f.Unlock(); err != nil {
+		t.Fatal(err)
+	}
+	if err := f.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err := f.Lock(); !errors.Is(err, fs.ErrClosed) {
+		t.Fatalf("was expecting ErrClosed, got %v", err)
+	}
+	if _, err := f.TryLock(); !errors.Is(err, fs.ErrClosed) {
+		t.Fatalf("was expecting ErrClosed, got %v", err)
+	}
+}
+
+func TestFileXattr(t *testing.T) {
+	t.Parallel()
+	f := memfs.NewFile("foo", dMode, dTime, nil).WithXattrs(map[string][]byte{
+		"user.b": []byte("2"),
+		"user.a": []byte("1"),
+	})
+	names, err := f.Listxattr()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(names) != 2 || names[0] != "user.a" || names[1] != "user.b" {
+		t.Fatalf("did not find expected names, got %v", names)
+	}
+	data := []byte("3")
+	if err := f.Setxattr("user.a", data); err != nil {
+		t.Fatal(err)
+	}
+	data[0] = 'x'
+	b, err := f.Getxattr("user.a")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(b) != "3" {
+		t.Fatalf("was expecting 3, got %s", b)
+	}
+	if err := f.Removexattr("user.a"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := f.Getxattr("user.a"); !errors.Is(err, fs.ErrNoAttr) {
+		t.Fatalf("was expecting ErrNoAttr, got %v", err)
+	}
+	if err := f.Removexattr("user.a"); !errors.Is(err, fs.ErrNoAttr) {
+		t.Fatalf("was expecting ErrNoAttr, got %v", err)
+	}
+	f.Close()
+	assertClosed := func(err error) {
+		t.Helper()
+		if !errors.Is(err, fs.ErrClosed) {
+			t.Fatalf("was expecting ErrClosed, got %v", err)
+		}
+	}
+	_, err = f.Listxattr()
+	assertClosed(err)
+	_, err = f.Getxattr("user.b")
+	assertClosed(err)
+	assertClosed(f.Setxattr("user.a", data))
+	assertClosed(f.Removexattr("user.b"))
+}
+
+func TestFileTimes(t *testing.T) {
+	t.Parallel()
+	mtime := time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC)
+	f := memfs.NewFile("foo", dMode, mtime, nil)
+	fi, err := f.Stat()
+	if err != nil {
+		t.Fatal(err)
+	}
+	times := fi.(fs.TimesFileInfo).Times()
+	expected := fs.Times{Atime: mtime, Mtime: mtime, Ctime: mtime, Btime: mtime}
+	if times != expected {
+		t.Fatalf("was expecting %v, got %v", expected, times)
+	}
+
+	if err := f.Chmod(0600); err != nil {
+		t.Fatal(err)
+	}
+	if fi, err = f.Stat(); err != nil {
+		t.Fatal(err)
+	}
+	times = fi.(fs.TimesFileInfo).Times()
+	if !times.Mtime.Equal(mtime) || !times.Ctime.After(mtime) {
+		t.Fatalf("was expecting only the change time to be updated, got %v", times)
+	}
+
+	if _, err := f.Write([]byte("a")); err != nil {
+		t.Fatal(err)
+	}
+	if fi, err = f.Stat(); err != nil {
+		t.Fatal(err)
+	}
+	times = fi.(fs.TimesFileInfo).Times()
+	if !times.Mtime.After(mtime) || !times.Atime.Equal(mtime) || !times.Btime.Equal(mtime) {
+		t.Fatalf("was expecting only the modification and change times to be updated, got %v", times)
+	}
+
+	atime := mtime.Add(time.Hour)
+	if err := f.Chtimes(atime, mtime); err != nil {
+		t.Fatal(err)
+	}
+	if fi, err = f.Stat(); err != nil {
+		t.Fatal(err)
+	}
+	times = fi.(fs.TimesFileInfo).Times()
+	if !times.Atime.Equal(atime) || !times.Mtime.Equal(mtime) {
+		t.Fatalf("did not find expected times, got %v", times)
+	}
+}
+
+func TestFileStatSnapshot(t *testing.T) {
+	t.Parallel()
+	mtime := time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC)
+	s := memfs.NewWithFiles(map[string]fs.File{
+		"foo": memfs.NewFile("foo", dMode, mtime, []byte("foo")),
+	})
+	f, err := s.Open("foo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	fi, err := f.Stat()
+	if err != nil {
+		t.Fatal(err)
+	}
+	sfi, err := fsutil.Stat(s, "foo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	infos, err := fsutil.ReadDir(s, ".")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := fsutil.WriteFile(s, "foo", []byte("foobar"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	for _, fi := range []os.FileInfo{fi, sfi, infos[0]} {
+		if fi.Size() != 3 || !fi.ModTime().Equal(mtime) {
+			t.Fatalf("was expecting the FileInfo to be unchanged, got %d %s", fi.Size(), fi.ModTime())
+		}
+	}
+	if fi, err = f.Stat(); err != nil {
+		t.Fatal(err)
+	}
+	if fi.Size() != 6 || fi.ModTime().Equal(mtime) {
+		t.Fatalf("was expecting a new FileInfo with the changes, got %d %s", fi.Size(), fi.ModTime())
+	}
+}
+
+func TestFileReadFromWriteTo(t *testing.T) {
+	t.Parallel()
+	f := memfs.NewFile("foo", dMode, dTime, nil)
+	n, err := f.ReadFrom(bytes.NewReader([]byte("0123456789")))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n != 10 {
+		t.Fatalf("was expecting 10 bytes, got %d", n)
+	}
+	if _, err := f.Seek(4, io.SeekStart); err != nil {
+		t.Fatal(err)
+	}
+	var buf bytes.Buffer
+	if n, err = f.WriteTo(&buf); err != nil {
+		t.Fatal(err)
+	}
+	if n != 6 || buf.String() != "456789" {
+		t.Fatalf("was expecting 456789, got %d %s", n, buf.String())
+	}
+	if n, err = f.WriteTo(&buf); err != nil || n != 0 {
+		t.Fatalf("was expecting nothing to be written, got %d %v", n, err)
+	}
+	f.Close()
+	if _, err := f.WriteTo(&buf); !errors.Is(err, fs.ErrClosed) {
+		t.Fatalf("was expecting ErrClosed, got %v", err)
+	}
+	if _, err := f.ReadFrom(&buf); !errors.Is(err, fs.ErrClosed) {
+		t.Fatalf("was expecting ErrClosed, got %v", err)
+	}
+}
+
+func TestFileConcurrent(t *testing.T) {
+	t.Parallel()
+	f := memfs.NewFile("foo", dMode, dTime, nil)
+	s := memfs.NewSystem(map[string]fs.File{"foo": f})
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 100; j++ {
+				if _, err := f.Write([]byte("0123456789")); err != nil {
+					t.Error(err)
+					return
+				}
+			}
+		}()
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 100; j++ {
+				r, err := s.Open("foo")
+				if err != nil {
+					t.Error(err)
+					return
+				}
+				if _, err := ioutil.ReadAll(r); err != nil {
+					t.Error(err)
+				}
+				if _, err := r.Stat(); err != nil {
+					t.Error(err)
+				}
+				r.Close()
+			}
+		}()
+	}
+	wg.Wait()
+	fi, err := f.Stat()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fi.Size() != 4000 {
+		t.Fatalf("was expecting size 4000, got %d", fi.Size())
+	}
+}
@@ -2,102 +2,224 @@ package memfs
 
 import (
 	"os"
+	"sync"
 	"time"
+
+	"github.com/daaku/go.fs"
 )
 
 // Literal definition of a FileInfo that can be converted to an os.FileInfo.
-// This provides a convinent way to define them.
+// This provides a convinent way to define them. The access, change and birth
+// times default to the ModTime.
 type FileInfo struct {
-	Name    string
-	Size    int64
-	Mode    os.FileMode
-	ModTime time.Time
-	Sys     interface{}
+	Name       string
+	Size       int64
+	Mode       os.FileMode
+	ModTime    time.Time
+	AccessTime time.Time
+	ChangeTime time.Time
+	BirthTime  time.Time
+	Sys        interface{}
 }
 
 // Since the interface and field names conflict, we copy over the data to this
-// inernal struct that satisfies the public os.FileInfo interface.
+// inernal struct that satisfies the public os.FileInfo interface. It is safe
+// for concurrent use, since it is shared with the directory listings.
 type MemFileInfo struct {
-	name    string
-	size    int64
-	mode    os.FileMode
-	modTime time.Time
-	sys     interface{}
+	mu         sync.RWMutex
+	name       string
+	size       int64
+	mode       os.FileMode
+	modTime    time.Time
+	accessTime time.Time
+	changeTime time.Time
+	birthTime  time.Time
+	sys        interface{}
 }
 
 // Create a new in-memory FileInfo based on the configured FileInfo.
 func NewFileInfo(fi FileInfo) *MemFileInfo {
 	return &MemFileInfo{
-		name:    fi.Name,
-		size:    fi.Size,
-		mode:    fi.Mode,
-		modTime: fi.ModTime,
-		sys:     fi.Sys,
+		name:       fi.Name,
+		size:       fi.Size,
+		mode:       fi.Mode,
+		modTime:    fi.ModTime,
+		accessTime: orTime(fi.AccessTime, fi.ModTime),
+		changeTime: orTime(fi.ChangeTime, fi.ModTime),
+		birthTime:  orTime(fi.BirthTime, fi.ModTime),
+		sys:        fi.Sys,
 	}
 }
 
-// Create a new in-memory FileInfo based on a os.FileInfo.
+// Create a new in-memory FileInfo based on a os.FileInfo. The access, change
+// and birth times are copied if it is a fs.TimesFileInfo.
 func NewFileInfoFromExisting(fi os.FileInfo) *MemFileInfo {
+	var times fs.Times
+	if tfi, ok := fi.(fs.TimesFileInfo); ok {
+		times = tfi.Times()
+	}
+	return &MemFileInfo{
+		name:       fi.Name(),
+		size:       fi.Size(),
+		mode:       fi.Mode(),
+		modTime:    fi.ModTime(),
+		accessTime: orTime(times.Atime, fi.ModTime()),
+		changeTime: orTime(times.Ctime, fi.ModTime()),
+		birthTime:  orTime(times.Btime, fi.ModTime()),
+		sys:        fi.Sys(),
+	}
+}
+
+// Returns a copy of the FileInfo.
+func (fi *MemFileInfo) clone() *MemFileInfo {
+	fi.mu.RLock()
+	defer fi.mu.RUnlock()
 	return &MemFileInfo{
-		name:    fi.Name(),
-		size:    fi.Size(),
-		mode:    fi.Mode(),
-		modTime: fi.ModTime(),
-		sys:     fi.Sys(),
+		name:       fi.name,
+		size:       fi.size,
+		mode:       fi.mode,
+		modTime:    fi.modTime,
+		accessTime: fi.accessTime,
+		changeTime: fi.changeTime,
+		birthTime:  fi.birthTime,
+		sys:        fi.sys,
 	}
 }
 
+func orTime(t, fallback time.Time) time.Time {
+	if t.IsZero() {
+		return fallback
+	}
+	return t
+}
+
 // Base name for file.
 func (fi *MemFileInfo) Name() string {
+	fi.mu.RLock()
+	defer fi.mu.RUnlock()
 	return fi.name
 }
 
 // Set base name for file.
 func (fi *MemFileInfo) SetName(name string) {
+	fi.mu.Lock()
+	defer fi.mu.Unlock()
 	fi.name = name
 }
 
 // Length in bytes for file.
 func (fi *MemFileInfo) Size() int64 {
+	fi.mu.RLock()
+	defer fi.mu.RUnlock()
 	return fi.size
 }
 
 // Set length in bytes for file.
 func (fi *MemFileInfo) SetSize(size int64) {
+	fi.mu.Lock()
+	defer fi.mu.Unlock()
 	fi.size = size
 }
 
 // File mode bits for file.
 func (fi *MemFileInfo) Mode() os.FileMode {
+	fi.mu.RLock()
+	defer fi.mu.RUnlock()
 	return fi.mode
 }
 
 // Set file mode bits for file.
 func (fi *MemFileInfo) SetMode(mode os.FileMode) {
+	fi.mu.Lock()
+	defer fi.mu.Unlock()
 	fi.mode = mode
 }
 
 // Modification time for file.
 func (fi *MemFileInfo) ModTime() time.Time {
+	fi.mu.RLock()
+	defer fi.mu.RUnlock()
 	return fi.modTime
 }
 
 // Set modification time for file.
 func (fi *MemFileInfo) SetModTime(t time.Time) {
+	fi.mu.Lock()
+	defer fi.mu.Unlock()
 	fi.modTime = t
 }
 
+// Access time for file.
+func (fi *MemFileInfo) AccessTime() time.Time {
+	fi.mu.RLock()
+	defer fi.mu.RUnlock()
+	return fi.accessTime
+}
+
+// Set access time for file.
+func (fi *MemFileInfo) SetAccessTime(t time.Time) {
+	fi.mu.Lock()
+	defer fi.mu.Unlock()
+	fi.accessTime = t
+}
+
+// Change time for file.
+func (fi *MemFileInfo) ChangeTime() time.Time {
+	fi.mu.RLock()
+	defer fi.mu.RUnlock()
+	return fi.changeTime
+}
+
+// Set change time for file.
+func (fi *MemFileInfo) SetChangeTime(t time.Time) {
+	fi.mu.Lock()
+	defer fi.mu.Unlock()
+	fi.changeTime = t
+}
+
+// Birth time for file.
+func (fi *MemFileInfo) BirthTime() time.Time {
+	fi.mu.RLock()
+	defer fi.mu.RUnlock()
+	return fi.birthTime
+}
+
+// Set birth time for file.
+func (fi *MemFileInfo) SetBirthTime(t time.Time) {
+	fi.mu.Lock()
+	defer fi.mu.Unlock()
+	fi.birthTime = t
+}
+
+// All the timestamps for file.
+func (fi *MemFileInfo) Times() fs.Times {
+	fi.mu.RLock()
+	defer fi.mu.RUnlock()
+	return fs.Times{
+		Atime: fi.accessTime,
+		Mtime: fi.modTime,
+		Ctime: fi.changeTime,
+		Btime: fi.birthTime,
+	}
+}
+
 // Abbreviation for Mode().IsDir().
 func (fi *MemFileInfo) IsDir() bool {
+	fi.mu.RLock()
+	defer fi.mu.RUnlock()
 	return fi.mode.IsDir()
 }
 
 // System specific data.
 func (fi *MemFileInfo) Sys() interface{} {
+	fi.mu.RLock()
+	defer fi.mu.RUnlock()
 	return fi.sys
 }
 
 // Set system specific data.
 func (fi *MemFileInfo) SetSys(sys interface{}) {
+	fi.mu.Lock()
+	defer fi.mu.Unlock()
 	fi.sys = sys
 }
@@ -0,0 +1,208 @@
+package memfs
+
+import (
+	"io/ioutil"
+	"maps"
+	"os"
+	"path"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/daaku/go.fs"
+	"github.com/daaku/go.fs/emptyfs"
+	"github.com/daaku/go.fs/fsutil"
+)
+
+// FixtureConfig configures the Files created by NewFromMap, NewFromTxtar and
+// NewFromDir.
+type FixtureConfig struct {
+	FileMode os.FileMode // for files, defaults to 0644
+	DirMode  os.FileMode // for directories, defaults to 0755
+	ModTime  time.Time   // for all files, defaults to the current time
+	Clock    fs.Clock    // for the System, also used for the default ModTime
+}
+
+// NewFromMap creates a System with a File for each entry in the map, along
+// with the directories containing them. Names ending in a slash create empty
+// directories. The names are added in sorted order, so directory listings are
+// sorted too.
+func NewFromMap(files map[string]string, c FixtureConfig) fs.System {
+	x := newFixture(c)
+	for _, name := range slices.Sorted(maps.Keys(files)) {
+		if err := x.add(name, []byte(files[name])); err != nil {
+			return emptyfs.NewWithError(err)
+		}
+	}
+	return NewSystemWithConfig(x.files, Config{Clock: x.Clock})
+}
+
+// NewFromTxtar creates a System with a File for each file in the txtar
+// archive, along with the directories containing them. Names ending in a slash
+// create empty directories. The leading comment in the archive is ignored.
+func NewFromTxtar(archive []byte, c FixtureConfig) fs.System {
+	x := newFixture(c)
+	for _, f := range parseTxtar(archive) {
+		if err := x.add(f.name, f.data); err != nil {
+			return emptyfs.NewWithError(err)
+		}
+	}
+	return NewSystemWithConfig(x.files, Config{Clock: x.Clock})
+}
+
+// NewFromDir creates a System with a copy of the files in the directory on
+// disk, like a testdata directory. The modes and modification times are taken
+// from disk, unless they are set in the config. Entries other than regular
+// files and directories, like symbolic links, are skipped.
+func NewFromDir(dir string, c FixtureConfig) fs.System {
+	x := newFixture(c)
+	err := filepath.Walk(dir, func(name string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(dir, name)
+		if err != nil {
+			return err
+		}
+		rel = filepath.ToSlash(rel)
+		mode, mtime := info.Mode()&os.ModePerm, info.ModTime()
+		if !c.ModTime.IsZero() {
+			mtime = c.ModTime
+		}
+		if info.IsDir() {
+			if c.DirMode != 0 {
+				mode = c.DirMode
+			}
+			return x.put(rel, NewDir(rel, mode, mtime, nil))
+		}
+		if !info.Mode().IsRegular() {
+			return nil
+		}
+		data, err := ioutil.ReadFile(name)
+		if err != nil {
+			return err
+		}
+		if c.FileMode != 0 {
+			mode = c.FileMode
+		}
+		return x.put(rel, NewFile(rel, mode, mtime, data))
+	})
+	if err != nil {
+		return emptyfs.NewWithError(err)
+	}
+	return NewSystemWithConfig(x.files, Config{Clock: x.Clock})
+}
+
+// DumpTxtar returns a txtar archive of the files in the System under root,
+// which is useful for golden files. Empty directories are included as names
+// ending in a slash, and since txtar ends every file with a newline one is
+// added to files that don't end with one.
+func DumpTxtar(s fs.System, root string) ([]byte, error) {
+	var files []txtarFile
+	err := fsutil.Walk(s, root, func(name string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		rel := strings.TrimPrefix(strings.TrimPrefix(name, root), "/")
+		if root == "." {
+			rel = name
+		}
+		switch {
+		case info.IsDir():
+			infos, err := fsutil.ReadDir(s, name)
+			if err != nil {
+				return err
+			}
+			if len(infos) == 0 && rel != "." && rel != "" {
+				files = append(files, txtarFile{name: rel + "/"})
+			}
+		case info.Mode().IsRegular():
+			data, err := fsutil.ReadFile(s, name)
+			if err != nil {
+				return err
+			}
+			files = append(files, txtarFile{name: rel, data: data})
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return formatTxtar(files), nil
+}
+
+// Builds the Files for a System, creating directories as necessary.
+type fixture struct {
+	FixtureConfig
+	files map[string]fs.File
+}
+
+func newFixture(c FixtureConfig) *fixture {
+	if c.FileMode == 0 {
+		c.FileMode = 0644
+	}
+	if c.DirMode == 0 {
+		c.DirMode = 0755
+	}
+	if c.Clock == nil {
+		c.Clock = fs.SystemClock
+	}
+	if c.ModTime.IsZero() {
+		c.ModTime = c.Clock.Now()
+	}
+	return &fixture{
+		FixtureConfig: c,
+		files: map[string]fs.File{
+			".": NewDir(".", c.DirMode, c.ModTime, nil),
+		},
+	}
+}
+
+// Adds a File with the data, or an empty directory if the name ends in a
+// slash.
+func (x *fixture) add(name string, data []byte) error {
+	clean := fsutil.CleanRelative(name)
+	if strings.HasSuffix(name, "/") {
+		_, err := x.dir(clean)
+		return err
+	}
+	return x.put(clean, NewFile(clean, x.FileMode, x.ModTime, data))
+}
+
+// Puts the File in the System, replacing the root directory or creating the
+// missing parent directories as necessary.
+func (x *fixture) put(name string, f *File) error {
+	if name == "." {
+		x.files[name] = f
+		return nil
+	}
+	if x.files[name] != nil {
+		return &fs.PathError{Op: "open", Path: name, Err: fs.ErrExist}
+	}
+	p, err := x.dir(path.Dir(name))
+	if err != nil {
+		return err
+	}
+	if err := p.AddDirInfo(f.fileInfo); err != nil {
+		return err
+	}
+	x.files[name] = f
+	return nil
+}
+
+// Returns the named directory, creating it if necessary.
+func (x *fixture) dir(name string) (*File, error) {
+	if f := x.files[name]; f != nil {
+		d := f.(*File)
+		if !d.isDir {
+			return nil, &fs.PathError{Op: "mkdir", Path: name, Err: fs.ErrNotDir}
+		}
+		return d, nil
+	}
+	d := NewDir(name, x.DirMode, x.ModTime, nil)
+	if err := x.put(name, d); err != nil {
+		return nil, err
+	}
+	return d, nil
+}
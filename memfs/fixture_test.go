@@ -0,0 +1,252 @@
+package memfs_test
+
+import (
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/daaku/go.fs"
+	"github.com/daaku/go.fs/fstest"
+	"github.com/daaku/go.fs/fsutil"
+	"github.com/daaku/go.fs/memfs"
+)
+
+const archive = `comment is ignored
+-- foo.txt --
+foo
+-- d/bar.txt --
+bar
+-- d/e/ --
+`
+
+func TestNewFromMap(t *testing.T) {
+	t.Parallel()
+	mtime := time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC)
+	s := memfs.NewFromMap(map[string]string{
+		"foo.txt":   "foo",
+		"d/bar.txt": "bar",
+		"d/e/":      "",
+	}, memfs.FixtureConfig{FileMode: 0600, ModTime: mtime})
+	b, err := fsutil.ReadFile(s, "d/bar.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(b) != "bar" {
+		t.Fatalf("was expecting bar, got %s", b)
+	}
+	for name, mode := range map[string]os.FileMode{
+		"foo.txt": 0600,
+		"d":       os.ModeDir | 0755,
+		"d/e":     os.ModeDir | 0755,
+	} {
+		fi, err := fsutil.Stat(s, name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if fi.Mode() != mode {
+			t.Fatalf("%s: was expecting mode %s, got %s", name, mode, fi.Mode())
+		}
+		if !fi.ModTime().Equal(mtime) {
+			t.Fatalf("%s: was expecting time %s, got %s", name, mtime, fi.ModTime())
+		}
+	}
+	infos, err := fsutil.ReadDir(s, "d")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(infos) != 2 || infos[0].Name() != "bar.txt" || infos[1].Name() != "e" {
+		t.Fatalf("was expecting bar.txt and e, got %v", infos)
+	}
+}
+
+func TestNewFromMapConflict(t *testing.T) {
+	t.Parallel()
+	s := memfs.NewFromMap(map[string]string{
+		"foo":     "foo",
+		"foo/bar": "bar",
+	}, memfs.FixtureConfig{})
+	if _, err := s.Open("foo"); !errors.Is(err, fs.ErrNotDir) {
+		t.Fatalf("was expecting ErrNotDir, got %v", err)
+	}
+}
+
+func TestNewFromMapSorted(t *testing.T) {
+	t.Parallel()
+	files := map[string]string{}
+	var expected []string
+	for c := 'a'; c <= 'z'; c++ {
+		files["d/"+string(c)] = ""
+		expected = append(expected, string(c))
+	}
+	s := memfs.NewFromMap(files, memfs.FixtureConfig{})
+	d, err := s.Open("d")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer d.Close()
+	names, err := d.Readdirnames(0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !slices.Equal(names, expected) {
+		t.Fatalf("was expecting %v, got %v", expected, names)
+	}
+}
+
+func TestNewFromMapClock(t *testing.T) {
+	t.Parallel()
+	start := time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC)
+	clock := fstest.NewFakeClock(start)
+	s := memfs.NewFromMap(map[string]string{"d/foo.txt": "foo"},
+		memfs.FixtureConfig{Clock: clock})
+	fi, err := fsutil.Stat(s, "d")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !fi.ModTime().Equal(start) {
+		t.Fatalf("was expecting time %s, got %s", start, fi.ModTime())
+	}
+	mtime := clock.Advance(time.Hour)
+	if err := fsutil.WriteFile(s, "d/foo.txt", []byte("bar"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if fi, err = fsutil.Stat(s, "d/foo.txt"); err != nil {
+		t.Fatal(err)
+	}
+	if !fi.ModTime().Equal(mtime) {
+		t.Fatalf("was expecting time %s, got %s", mtime, fi.ModTime())
+	}
+}
+
+func TestNewFromTxtar(t *testing.T) {
+	t.Parallel()
+	s := memfs.NewFromTxtar([]byte(archive), memfs.FixtureConfig{})
+	b, err := fsutil.ReadFile(s, "foo.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(b) != "foo\n" {
+		t.Fatalf("was expecting foo, got %q", b)
+	}
+	fi, err := fsutil.Stat(s, "d/e")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !fi.IsDir() {
+		t.Fatal("was expecting dir")
+	}
+	if fi.Mode().Perm() != 0755 {
+		t.Fatalf("was expecting default mode, got %s", fi.Mode())
+	}
+}
+
+func TestNewFromDir(t *testing.T) {
+	t.Parallel()
+	dir, err := ioutil.TempDir("", "memfs_test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	if err := os.MkdirAll(filepath.Join(dir, "d", "e"), 0700); err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(filepath.Join(dir, "foo.txt"), []byte("foo\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(filepath.Join(dir, "d", "bar.txt"), []byte("bar\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	mtime := time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC)
+	if err := os.Chtimes(filepath.Join(dir, "foo.txt"), mtime, mtime); err != nil {
+		t.Fatal(err)
+	}
+	s := memfs.NewFromDir(dir, memfs.FixtureConfig{})
+	fi, err := fsutil.Stat(s, "foo.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fi.Mode() != 0600 || !fi.ModTime().Equal(mtime) {
+		t.Fatalf("was expecting the mode and time from disk, got %s %s", fi.Mode(), fi.ModTime())
+	}
+	fi, err = fsutil.Stat(s, "d/e")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fi.Mode() != os.ModeDir|0700 {
+		t.Fatalf("was expecting the mode from disk, got %s", fi.Mode())
+	}
+	actual, err := memfs.DumpTxtar(s, ".")
+	if err != nil {
+		t.Fatal(err)
+	}
+	const expected = "-- d/bar.txt --\nbar\n-- d/e/ --\n-- foo.txt --\nfoo\n"
+	if string(actual) != expected {
+		t.Fatalf("was expecting %q, got %q", expected, actual)
+	}
+}
+
+func TestNewFromDirSymlink(t *testing.T) {
+	t.Parallel()
+	dir, err := ioutil.TempDir("", "memfs_test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	if err := os.Mkdir(filepath.Join(dir, "d"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(filepath.Join(dir, "d", "foo.txt"), []byte("foo\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	for name, target := range map[string]string{"dlink": "d", "flink": "d/foo.txt"} {
+		if err := os.Symlink(target, filepath.Join(dir, name)); err != nil {
+			t.Skip(err)
+		}
+	}
+	s := memfs.NewFromDir(dir, memfs.FixtureConfig{})
+	actual, err := memfs.DumpTxtar(s, ".")
+	if err != nil {
+		t.Fatal(err)
+	}
+	const expected = "-- d/foo.txt --\nfoo\n"
+	if string(actual) != expected {
+		t.Fatalf("was expecting %q, got %q", expected, actual)
+	}
+}
+
+func TestNewFromDirNotExist(t *testing.T) {
+	t.Parallel()
+	s := memfs.NewFromDir(filepath.Join(os.TempDir(), "memfs_test_missing"), memfs.FixtureConfig{})
+	if _, err := s.Open("."); !s.IsNotExist(err) {
+		t.Fatalf("was expecting is not exist error, got %v", err)
+	}
+}
+
+func TestDumpTxtarRoundTrip(t *testing.T) {
+	t.Parallel()
+	s := memfs.NewFromTxtar([]byte(archive), memfs.FixtureConfig{})
+	actual, err := memfs.DumpTxtar(s, "d")
+	if err != nil {
+		t.Fatal(err)
+	}
+	const expected = "-- bar.txt --\nbar\n-- e/ --\n"
+	if string(actual) != expected {
+		t.Fatalf("was expecting %q, got %q", expected, actual)
+	}
+	if _, err := memfs.DumpTxtar(s, "missing"); !s.IsNotExist(err) {
+		t.Fatalf("was expecting is not exist error, got %v", err)
+	}
+}
+
+func TestFixtureConformance(t *testing.T) {
+	t.Parallel()
+	fstest.TestSystem(t, fstest.Config{
+		New: func(t *testing.T, files map[string]string) (fs.System, string) {
+			return memfs.NewFromMap(files, memfs.FixtureConfig{}), "."
+		},
+	})
+}
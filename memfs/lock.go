@@ -0,0 +1,118 @@
+package memfs
+
+import (
+	"sync"
+
+	"github.com/daaku/go.fs"
+)
+
+// The in-process advisory lock for a file. Locks are owned by the File
+// holding them, and block other Files for the same file.
+type lock struct {
+	mu      sync.Mutex
+	cond    sync.Cond
+	writer  *File
+	readers map[*File]bool
+}
+
+func newLock() *lock {
+	l := &lock{readers: make(map[*File]bool)}
+	l.cond.L = &l.mu
+	return l
+}
+
+// Acquires the lock for the owner, waiting for it to become available if
+// wait is true. Returns false if it did not become available.
+func (l *lock) acquire(owner *File, exclusive, wait bool) bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	for !l.available(owner, exclusive) {
+		if !wait {
+			return false
+		}
+		l.cond.Wait()
+	}
+	if exclusive {
+		delete(l.readers, owner)
+		l.writer = owner
+	} else {
+		if l.writer == owner {
+			l.writer = nil
+			l.cond.Broadcast()
+		}
+		l.readers[owner] = true
+	}
+	return true
+}
+
+func (l *lock) available(owner *File, exclusive bool) bool {
+	if l.writer != nil && l.writer != owner {
+		return false
+	}
+	if exclusive {
+		for reader := range l.readers {
+			if reader != owner {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// Releases any lock held by the owner.
+func (l *lock) release(owner *File) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.writer == owner {
+		l.writer = nil
+	}
+	delete(l.readers, owner)
+	l.cond.Broadcast()
+}
+
+// Lock acquires an exclusive advisory lock on the file, blocking until it is
+// available.
+func (f *File) Lock() error {
+	if f.IsClosed() {
+		return f.pathError("lock", fs.ErrClosed)
+	}
+	f.lock.acquire(f, true, true)
+	return f.closedWhileLocking()
+}
+
+// RLock acquires a shared advisory lock on the file, blocking until it is
+// available.
+func (f *File) RLock() error {
+	if f.IsClosed() {
+		return f.pathError("lock", fs.ErrClosed)
+	}
+	f.lock.acquire(f, false, true)
+	return f.closedWhileLocking()
+}
+
+// TryLock is like Lock, but returns false instead of blocking.
+func (f *File) TryLock() (bool, error) {
+	if f.IsClosed() {
+		return false, f.pathError("lock", fs.ErrClosed)
+	}
+	return f.lock.acquire(f, true, false), nil
+}
+
+// Since the File may be closed while waiting for the lock, releases the lock
+// acquired after Close if that happened.
+func (f *File) closedWhileLocking() error {
+	if f.IsClosed() {
+		f.lock.release(f)
+		return f.pathError("lock", fs.ErrClosed)
+	}
+	return nil
+}
+
+// Unlock releases the lock held by the File, if any.
+func (f *File) Unlock() error {
+	if f.IsClosed() {
+		return f.pathError("unlock", fs.ErrClosed)
+	}
+	f.lock.release(f)
+	return nil
+}
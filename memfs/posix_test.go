@@ -0,0 +1,289 @@
+package memfs_test
+
+import (
+	"fmt"
+	"io"
+	"io/ioutil"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/daaku/go.fs"
+	"github.com/daaku/go.fs/fsutil"
+	"github.com/daaku/go.fs/memfs"
+	"github.com/daaku/go.fs/realfs"
+)
+
+// An operation applied to both a memfs and a realfs File. It returns a
+// description of the result, which is expected to be the same for both.
+type posixOp struct {
+	name string
+	fn   func(f fs.File) string
+}
+
+// Describes the result of an operation. Errors are compared by class only,
+// since the messages differ.
+func posixResult(n int64, data []byte, err error) string {
+	class := "ok"
+	switch {
+	case err == io.EOF:
+		class = "EOF"
+	case err != nil:
+		class = "error"
+	}
+	return fmt.Sprintf("%d %q %s", n, data, class)
+}
+
+func posixRead(size int) posixOp {
+	return posixOp{fmt.Sprintf("Read(%d)", size), func(f fs.File) string {
+		b := make([]byte, size)
+		n, err := f.Read(b)
+		return posixResult(int64(n), b[:n], err)
+	}}
+}
+
+func posixReadAt(size int, off int64) posixOp {
+	return posixOp{fmt.Sprintf("ReadAt(%d, %d)", size, off), func(f fs.File) string {
+		b := make([]byte, size)
+		n, err := f.ReadAt(b, off)
+		return posixResult(int64(n), b[:n], err)
+	}}
+}
+
+func posixWrite(data string) posixOp {
+	return posixOp{fmt.Sprintf("Write(%q)", data), func(f fs.File) string {
+		n, err := f.Write([]byte(data))
+		return posixResult(int64(n), nil, err)
+	}}
+}
+
+func posixWriteAt(data string, off int64) posixOp {
+	return posixOp{fmt.Sprintf("WriteAt(%q, %d)", data, off), func(f fs.File) string {
+		n, err := f.WriteAt([]byte(data), off)
+		return posixResult(int64(n), nil, err)
+	}}
+}
+
+func posixSeek(offset int64, whence int) posixOp {
+	return posixOp{fmt.Sprintf("Seek(%d, %d)", offset, whence), func(f fs.File) string {
+		ret, err := f.Seek(offset, whence)
+		return posixResult(ret, nil, err)
+	}}
+}
+
+func posixTruncate(size int64) posixOp {
+	return posixOp{fmt.Sprintf("Truncate(%d)", size), func(f fs.File) string {
+		return posixResult(0, nil, f.Truncate(size))
+	}}
+}
+
+func posixStat() posixOp {
+	return posixOp{"Stat()", func(f fs.File) string {
+		fi, err := f.Stat()
+		if err != nil {
+			return posixResult(0, nil, err)
+		}
+		return posixResult(fi.Size(), nil, nil)
+	}}
+}
+
+func posixReaddir(n int) posixOp {
+	return posixOp{fmt.Sprintf("Readdir(%d)", n), func(f fs.File) string {
+		infos, err := f.Readdir(n)
+		return posixResult(int64(len(infos)), nil, err)
+	}}
+}
+
+func posixClose() posixOp {
+	return posixOp{"Close()", func(f fs.File) string {
+		return posixResult(0, nil, f.Close())
+	}}
+}
+
+// Returns a sequence of random operations, with offsets and sizes around the
+// size of the initial contents.
+func posixRandomOps(r *rand.Rand, count int) []posixOp {
+	data := func() string {
+		return strings.Repeat(string(rune('a'+r.Intn(26))), r.Intn(6))
+	}
+	off := func() int64 { return int64(r.Intn(22) - 1) }
+	ops := make([]posixOp, count)
+	for i := range ops {
+		switch r.Intn(7) {
+		case 0:
+			ops[i] = posixRead(r.Intn(8))
+		case 1:
+			ops[i] = posixReadAt(r.Intn(8), off())
+		case 2:
+			ops[i] = posixWrite(data())
+		case 3:
+			ops[i] = posixWriteAt(data(), off())
+		case 4:
+			ops[i] = posixSeek(int64(r.Intn(26)-5), r.Intn(3))
+		case 5:
+			ops[i] = posixTruncate(off())
+		case 6:
+			ops[i] = posixStat()
+		}
+	}
+	return ops
+}
+
+// Runs the operations on a File opened with flag in both memfs and realfs,
+// comparing the results and the contents after every operation.
+func posixCompare(t *testing.T, dir string, flag int, ops []posixOp) {
+	t.Helper()
+	const initial = "0123456789"
+	m := memfs.NewWithFiles(map[string]fs.File{
+		"f": memfs.NewFile("f", 0644, time.Now(), []byte(initial)),
+	})
+	name := filepath.Join(dir, "f")
+	if err := ioutil.WriteFile(name, []byte(initial), 0644); err != nil {
+		t.Fatal(err)
+	}
+	mf, err := m.OpenFile("f", flag, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer mf.Close()
+	rf, err := realfs.New().OpenFile(name, flag, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rf.Close()
+
+	var done []string
+	for _, op := range ops {
+		done = append(done, op.name)
+		expected, actual := op.fn(rf), op.fn(mf)
+		if actual != expected {
+			t.Fatalf("flag %#o: after %s\nwas expecting %s, got %s",
+				flag, strings.Join(done, ", "), expected, actual)
+		}
+		expectedData, err := ioutil.ReadFile(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		actualData, err := fsutil.ReadFile(m, "f")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(actualData) != string(expectedData) {
+			t.Fatalf("flag %#o: after %s\nwas expecting contents %q, got %q",
+				flag, strings.Join(done, ", "), expectedData, actualData)
+		}
+	}
+}
+
+// Runs the operations on a directory in both memfs and realfs, comparing the
+// results. Only the number of entries is compared, since the order of a real
+// directory listing is unspecified.
+func posixCompareDir(t *testing.T, dir string, ops []posixOp) {
+	t.Helper()
+	names := []string{"a", "b", "c", "d", "e"}
+	files := map[string]fs.File{}
+	name, err := ioutil.TempDir(dir, "d")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, n := range names {
+		files["d/"+n] = memfs.NewFile(n, 0644, time.Now(), nil)
+		if err := ioutil.WriteFile(filepath.Join(name, n), nil, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	mf, err := memfs.NewWithFiles(files).Open("d")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer mf.Close()
+	rf, err := realfs.New().Open(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rf.Close()
+
+	var done []string
+	for _, op := range ops {
+		done = append(done, op.name)
+		expected, actual := op.fn(rf), op.fn(mf)
+		if actual != expected {
+			t.Fatalf("after %s\nwas expecting %s, got %s",
+				strings.Join(done, ", "), expected, actual)
+		}
+	}
+}
+
+var posixFlags = []int{
+	os.O_RDWR,
+	os.O_RDWR | os.O_APPEND,
+	os.O_RDONLY,
+	os.O_WRONLY,
+	os.O_WRONLY | os.O_APPEND,
+}
+
+func TestPOSIX(t *testing.T) {
+	t.Parallel()
+	dir, err := ioutil.TempDir("", "memfs_test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	scripted := []posixOp{
+		posixSeek(2, io.SeekEnd),
+		posixRead(4),
+		posixWrite("ab"),
+		posixSeek(-3, io.SeekEnd),
+		posixRead(8),
+		posixReadAt(4, 9),
+		posixReadAt(0, 20),
+		posixWriteAt("cd", 15),
+		posixTruncate(4),
+		posixTruncate(8),
+		posixReadAt(8, 0),
+		posixWrite(""),
+		posixSeek(-1, io.SeekCurrent),
+		posixSeek(-20, io.SeekCurrent),
+		posixSeek(0, 99),
+		posixStat(),
+	}
+	for _, flag := range posixFlags {
+		posixCompare(t, dir, flag, scripted)
+	}
+	for seed := int64(0); seed < 50; seed++ {
+		ops := posixRandomOps(rand.New(rand.NewSource(seed)), 40)
+		for _, flag := range posixFlags {
+			posixCompare(t, dir, flag, ops)
+		}
+	}
+}
+
+func TestPOSIXDir(t *testing.T) {
+	t.Parallel()
+	dir, err := ioutil.TempDir("", "memfs_test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	posixCompareDir(t, dir, []posixOp{
+		posixReaddir(2),
+		posixReaddir(0),
+		posixReaddir(1),
+		posixReaddir(0),
+		posixReaddir(-1),
+		posixClose(),
+		posixReaddir(0),
+		posixReaddir(1),
+	})
+	for seed := int64(0); seed < 50; seed++ {
+		r := rand.New(rand.NewSource(seed))
+		ops := make([]posixOp, 8)
+		for i := range ops {
+			ops[i] = posixReaddir(r.Intn(5) - 1)
+		}
+		posixCompareDir(t, dir, ops)
+	}
+}
@@ -0,0 +1,97 @@
+package memfs
+
+import (
+	"path"
+
+	"github.com/daaku/go.fs"
+)
+
+// A State is a copy of the files in a System at some point, as returned by
+// Snapshot. It shares the file contents with the System it was taken from, and
+// the contents are only copied when one side writes to them.
+type State struct {
+	files map[string]fs.File
+	clock fs.Clock
+}
+
+// Snapshot returns the current State of the System, which must be a System
+// from this package. It copies the names and metadata, but not the contents of
+// the files.
+func Snapshot(s fs.System) (*State, error) {
+	ms, ok := s.(system)
+	if !ok {
+		return nil, &fs.PathError{Op: "snapshot", Path: ".", Err: fs.ErrNotSupported}
+	}
+	ms.mu.RLock()
+	defer ms.mu.RUnlock()
+	return &State{files: cloneFiles(ms.files), clock: ms.clock}, nil
+}
+
+// Clone returns a new System starting with the files in the System, like
+// taking a Snapshot and creating a System from it. Changes to either System
+// aren't visible in the other.
+func Clone(s fs.System) (fs.System, error) {
+	st, err := Snapshot(s)
+	if err != nil {
+		return nil, err
+	}
+	return st.System(), nil
+}
+
+// System returns a new System starting in the State, using the Clock of the
+// System it was taken from. The State is left as is, so it can be used any
+// number of times.
+func (st *State) System() fs.System {
+	return NewSystemWithConfig(cloneFiles(st.files), Config{Clock: st.clock})
+}
+
+// Rollback restores the System to the State, which may have been taken from
+// another System. Files opened before the Rollback are detached from the
+// System, and fail with ErrClosed as if they were closed. Watchers are not
+// notified.
+func Rollback(s fs.System, st *State) error {
+	ms, ok := s.(system)
+	if !ok {
+		return &fs.PathError{Op: "rollback", Path: ".", Err: fs.ErrNotSupported}
+	}
+	files := cloneFiles(st.files)
+	ms.mu.Lock()
+	defer ms.mu.Unlock()
+	for _, f := range ms.files {
+		if mf, ok := f.(*File); ok {
+			mf.detach()
+		}
+	}
+	clear(ms.files)
+	for name, f := range files {
+		if mf, ok := f.(*File); ok {
+			mf.attach(ms, name)
+		}
+		ms.files[name] = f
+	}
+	return nil
+}
+
+// Returns copies of the Files, with the directory listings referring to the
+// copies. Entries that aren't from this package are shared as is.
+func cloneFiles(files map[string]fs.File) map[string]fs.File {
+	clones := make(map[string]fs.File, len(files))
+	for name, f := range files {
+		if mf, ok := f.(*File); ok {
+			f = mf.clone()
+		}
+		clones[name] = f
+	}
+	for name, f := range clones {
+		d, ok := f.(*File)
+		if !ok || !d.isDir {
+			continue
+		}
+		for i, fi := range d.infos {
+			if child, ok := clones[path.Join(name, fi.Name())].(*File); ok {
+				d.infos[i] = child.fileInfo
+			}
+		}
+	}
+	return clones
+}
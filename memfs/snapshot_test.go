@@ -0,0 +1,150 @@
+package memfs_test
+
+import (
+	"errors"
+	"os"
+	"testing"
+
+	"github.com/daaku/go.fs"
+	"github.com/daaku/go.fs/emptyfs"
+	"github.com/daaku/go.fs/fsutil"
+	"github.com/daaku/go.fs/memfs"
+)
+
+// Compares the System to the txtar archive.
+func assertTxtar(t *testing.T, s fs.System, expected string) {
+	t.Helper()
+	actual, err := memfs.DumpTxtar(s, ".")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(actual) != expected {
+		t.Fatalf("was expecting %q, got %q", expected, actual)
+	}
+}
+
+// Changes the System loaded from archive in a few ways.
+func changeSystem(t *testing.T, s fs.System) {
+	t.Helper()
+	f, err := s.OpenFile("foo.txt", os.O_WRONLY, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	if _, err := f.WriteAt([]byte("F"), 0); err != nil {
+		t.Fatal(err)
+	}
+	if err := fsutil.Truncate(s, "d/bar.txt", 1); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Remove("d/e"); err != nil {
+		t.Fatal(err)
+	}
+	if err := fsutil.WriteFile(s, "d/new.txt", []byte("new\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+const (
+	archiveFiles   = "-- d/bar.txt --\nbar\n-- d/e/ --\n-- foo.txt --\nfoo\n"
+	changedArchive = "-- d/bar.txt --\nb\n-- d/new.txt --\nnew\n-- foo.txt --\nFoo\n"
+)
+
+func TestClone(t *testing.T) {
+	t.Parallel()
+	s := memfs.NewFromTxtar([]byte(archive), memfs.FixtureConfig{})
+	c, err := memfs.Clone(s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	changeSystem(t, c)
+	assertTxtar(t, c, changedArchive)
+	assertTxtar(t, s, archiveFiles)
+
+	// the listing in the clone reflects the changes made to it
+	infos, err := fsutil.ReadDir(c, "d")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(infos) != 2 || infos[0].Size() != 1 || infos[1].Name() != "new.txt" {
+		t.Fatalf("was expecting bar.txt with size 1 and new.txt, got %v", infos)
+	}
+
+	// and changes in the original don't reach the clone
+	changeSystem(t, s)
+	if err := fsutil.WriteFile(s, "foo.txt", []byte("other"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	assertTxtar(t, c, changedArchive)
+}
+
+func TestSnapshotRollback(t *testing.T) {
+	t.Parallel()
+	s := memfs.NewFromTxtar([]byte(archive), memfs.FixtureConfig{})
+	st, err := memfs.Snapshot(s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	f, err := s.OpenFile("foo.txt", os.O_RDWR, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	for i := 0; i < 2; i++ {
+		changeSystem(t, s)
+		assertTxtar(t, s, changedArchive)
+		if err := memfs.Rollback(s, st); err != nil {
+			t.Fatal(err)
+		}
+		assertTxtar(t, s, archiveFiles)
+	}
+
+	// the File opened earlier is detached, and can't change the System
+	w, err := fsutil.Watch(s, ".", true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assertClosed := func(err error) {
+		t.Helper()
+		if !errors.Is(err, fs.ErrClosed) {
+			t.Fatalf("was expecting closed error, got %v", err)
+		}
+	}
+	_, err = f.WriteAt([]byte("x"), 0)
+	assertClosed(err)
+	_, err = f.Write([]byte("x"))
+	assertClosed(err)
+	_, err = f.Read(make([]byte, 1))
+	assertClosed(err)
+	assertClosed(f.Truncate(0))
+	_, err = f.Stat()
+	assertClosed(err)
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	for ev := range w.Events() {
+		t.Fatalf("was expecting no events, got %s", ev)
+	}
+	assertTxtar(t, s, archiveFiles)
+
+	// and the State can also start new Systems
+	assertTxtar(t, st.System(), archiveFiles)
+}
+
+func TestSnapshotNotSupported(t *testing.T) {
+	t.Parallel()
+	s := emptyfs.New()
+	if _, err := memfs.Snapshot(s); !errors.Is(err, fs.ErrNotSupported) {
+		t.Fatalf("was expecting ErrNotSupported, got %v", err)
+	}
+	if _, err := memfs.Clone(s); !errors.Is(err, fs.ErrNotSupported) {
+		t.Fatalf("was expecting ErrNotSupported, got %v", err)
+	}
+	st, err := memfs.Snapshot(memfs.NewSystem(nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := memfs.Rollback(s, st); !errors.Is(err, fs.ErrNotSupported) {
+		t.Fatalf("was expecting ErrNotSupported, got %v", err)
+	}
+}
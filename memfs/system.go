@@ -1,8 +1,12 @@
 package memfs
 
 import (
+	"errors"
 	"os"
-	"path/filepath"
+	"path"
+	"sort"
+	"strings"
+	"sync"
 	"time"
 
 	"github.com/daaku/go.fs"
@@ -10,28 +14,447 @@ import (
 	"github.com/daaku/go.fs/fsutil"
 )
 
-type system map[string]fs.File
+// The names are protected by mu, while the Files protect themselves. This
+// allows reads and writes of different files to proceed in parallel, and
+// only changes to the names are serialized.
+type system struct {
+	mu    *sync.RWMutex
+	files map[string]fs.File
+	watch *watchList
+	clock fs.Clock
+}
+
+// Config customizes a System.
+type Config struct {
+	Clock fs.Clock // for the timestamps set by the System, defaults to fs.SystemClock
+}
 
 func (s system) Open(name string) (fs.File, error) {
-	if f := s[name]; f != nil {
-		return f, nil
-	}
-	return nil, fsutil.NewErrNotFound(name)
+	return s.OpenFile(name, os.O_RDONLY, 0)
 }
 
 func (s system) IsNotExist(err error) bool {
 	return fsutil.IsNotExist(err)
 }
 
+func (s system) Stat(name string) (os.FileInfo, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.stat(name)
+}
+
+func (s system) stat(name string) (os.FileInfo, error) {
+	f := s.files[fsutil.CleanRelative(name)]
+	if f == nil {
+		return nil, &fs.PathError{Op: "stat", Path: name, Err: fs.ErrNotExist}
+	}
+	// Stat on the System works even if the File has been closed
+	if mf, ok := f.(*File); ok {
+		return mf.info(), nil
+	}
+	return f.Stat()
+}
+
+// ReadDir returns a sorted copy of the directory listing.
+func (s system) ReadDir(name string) ([]os.FileInfo, error) {
+	f, err := s.file("readdir", name)
+	if err != nil {
+		return nil, err
+	}
+	if !f.isDir {
+		return nil, f.pathError("readdir", fs.ErrNotDir)
+	}
+	f.node.mu.RLock()
+	infos := copyInfos(f.infos)
+	f.node.mu.RUnlock()
+	fsutil.SortByName(infos)
+	return infos, nil
+}
+
+// ReadFile returns a copy of the buffer, without opening a File.
+func (s system) ReadFile(name string) ([]byte, error) {
+	f, err := s.file("open", name)
+	if err != nil {
+		return nil, err
+	}
+	if f.isDir {
+		return nil, f.pathError("read", fs.ErrIsDir)
+	}
+	f.node.mu.RLock()
+	defer f.node.mu.RUnlock()
+	b := make([]byte, len(f.buf))
+	copy(b, f.buf)
+	return b, nil
+}
+
+func (s system) Chmod(name string, mode os.FileMode) error {
+	f, err := s.file("chmod", name)
+	if err != nil {
+		return err
+	}
+	return f.Chmod(mode)
+}
+
+func (s system) Chown(name string, uid, gid int) error {
+	f, err := s.file("chown", name)
+	if err != nil {
+		return err
+	}
+	return f.Chown(uid, gid)
+}
+
+func (s system) Chtimes(name string, atime time.Time, mtime time.Time) error {
+	f, err := s.file("chtimes", name)
+	if err != nil {
+		return err
+	}
+	return f.Chtimes(atime, mtime)
+}
+
+func (s system) Truncate(name string, size int64) error {
+	f, err := s.file("truncate", name)
+	if err != nil {
+		return err
+	}
+	return f.truncate(size)
+}
+
+func (s system) Create(name string) (fs.File, error) {
+	return s.OpenFile(name, os.O_RDWR|os.O_CREATE|os.O_TRUNC, 0666)
+}
+
+func (s system) OpenFile(name string, flag int, perm os.FileMode) (fs.File, error) {
+	name = fsutil.CleanRelative(name)
+	if flag&os.O_CREATE != 0 {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+	} else {
+		s.mu.RLock()
+		defer s.mu.RUnlock()
+	}
+	if f := s.files[name]; f != nil {
+		if flag&(os.O_CREATE|os.O_EXCL) == os.O_CREATE|os.O_EXCL {
+			return nil, &fs.PathError{Op: "open", Path: name, Err: fs.ErrExist}
+		}
+		mf, ok := f.(*File)
+		if ok {
+			if mf.isDir && fsutil.WantsWrite(flag) {
+				return nil, &fs.PathError{Op: "open", Path: name, Err: fs.ErrIsDir}
+			}
+			mf = mf.open(flag)
+			f = mf
+		}
+		if flag&os.O_TRUNC != 0 {
+			truncate := f.Truncate
+			if ok {
+				truncate = mf.truncate
+			}
+			if err := truncate(0); err != nil {
+				return nil, err
+			}
+		}
+
+		// Files handle O_APPEND on every Write
+		if flag&os.O_APPEND != 0 && !ok {
+			if _, err := f.Seek(0, os.SEEK_END); err != nil {
+				return nil, err
+			}
+		}
+		return f, nil
+	}
+	if flag&os.O_CREATE == 0 {
+		return nil, &fs.PathError{Op: "open", Path: name, Err: fs.ErrNotExist}
+	}
+	f := NewFile(name, perm, s.clock.Now(), nil)
+	if err := s.add("open", name, f); err != nil {
+		return nil, err
+	}
+	return f.open(flag), nil
+}
+
+func (s system) Mkdir(name string, perm os.FileMode) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.mkdir(name, perm)
+}
+
+func (s system) mkdir(name string, perm os.FileMode) error {
+	name = fsutil.CleanRelative(name)
+	if s.files[name] != nil {
+		return &fs.PathError{Op: "mkdir", Path: name, Err: fs.ErrExist}
+	}
+	return s.add("mkdir", name, NewDir(name, perm, s.clock.Now(), nil))
+}
+
+func (s system) MkdirAll(name string, perm os.FileMode) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.mkdirAll(name, perm)
+}
+
+func (s system) mkdirAll(name string, perm os.FileMode) error {
+	name = fsutil.CleanRelative(name)
+	if s.files[name] != nil {
+		fi, err := s.stat(name)
+		if err != nil {
+			return err
+		}
+		if !fi.IsDir() {
+			return &fs.PathError{Op: "mkdir", Path: name, Err: fs.ErrNotDir}
+		}
+		return nil
+	}
+	if name == "." {
+		return nil
+	}
+	if err := s.mkdirAll(path.Dir(name), perm); err != nil {
+		return err
+	}
+	return s.mkdir(name, perm)
+}
+
+func (s system) Remove(name string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.remove(name)
+}
+
+func (s system) remove(name string) error {
+	name = fsutil.CleanRelative(name)
+	if name == "." {
+		return &fs.PathError{Op: "remove", Path: name, Err: fs.ErrInvalid}
+	}
+	f := s.files[name]
+	if f == nil {
+		return &fs.PathError{Op: "remove", Path: name, Err: fs.ErrNotExist}
+	}
+	if mf, ok := f.(*File); ok && mf.isDir && !mf.empty() {
+		return &fs.PathError{Op: "remove", Path: name, Err: fs.ErrNotEmpty}
+	}
+	if err := s.unlink("remove", name); err != nil {
+		return err
+	}
+	s.watch.notify(name, fs.OpRemove)
+	return nil
+}
+
+func (s system) RemoveAll(name string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	name = fsutil.CleanRelative(name)
+	if s.files[name] == nil {
+		return nil
+	}
+	prefix := name + "/"
+	for child := range s.files {
+		if (name == "." && child != ".") || strings.HasPrefix(child, prefix) {
+			if mf, ok := s.files[child].(*File); ok {
+				mf.unwatch()
+			}
+			delete(s.files, child)
+			s.watch.notify(child, fs.OpRemove)
+		}
+	}
+
+	// the root directory itself is kept, but emptied
+	if name == "." {
+		if root, ok := s.files["."].(*File); ok && root.isDir {
+			if err := root.SetDirInfos(nil); err != nil {
+				return err
+			}
+			root.entriesChanged()
+		}
+		return nil
+	}
+	if err := s.unlink("remove", name); err != nil {
+		return err
+	}
+	s.watch.notify(name, fs.OpRemove)
+	return nil
+}
+
+func (s system) Rename(oldname, newname string) error {
+	oldname = fsutil.CleanRelative(oldname)
+	newname = fsutil.CleanRelative(newname)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if oldname == newname {
+		return nil
+	}
+	if oldname == "." || strings.HasPrefix(newname, oldname+"/") {
+		return renameError(oldname, newname, fs.ErrInvalid)
+	}
+	f, ok := s.files[oldname].(*File)
+	if !ok {
+		if s.files[oldname] == nil {
+			return renameError(oldname, newname, fs.ErrNotExist)
+		}
+		return renameError(oldname, newname, fs.ErrNotSupported)
+	}
+	if s.files[newname] != nil {
+		fi, err := s.stat(newname)
+		if err != nil {
+			return err
+		}
+		switch {
+		case fi.IsDir() && !f.isDir:
+			return renameError(oldname, newname, fs.ErrIsDir)
+		case !fi.IsDir() && f.isDir:
+			return renameError(oldname, newname, fs.ErrNotDir)
+		case fi.IsDir():
+			if err := s.remove(newname); err != nil {
+				return renameError(oldname, newname, errors.Unwrap(err))
+			}
+		default:
+			if err := s.unlink("rename", newname); err != nil {
+				return err
+			}
+		}
+	}
+	if _, err := s.parent("rename", newname); err != nil {
+		return err
+	}
+
+	if err := s.unlink("rename", oldname); err != nil {
+		return err
+	}
+	s.watch.notify(oldname, fs.OpRename)
+	if f.isDir {
+		prefix := oldname + "/"
+		for child, cf := range s.files {
+			if strings.HasPrefix(child, prefix) {
+				moved := newname + child[len(oldname):]
+				if mf, ok := cf.(*File); ok {
+					mf.SetName(moved)
+					mf.attach(s, moved)
+				}
+				delete(s.files, child)
+				s.files[moved] = cf
+			}
+		}
+	}
+	f.SetName(newname)
+	return s.add("rename", newname, f)
+}
+
+// Returns the stored *File for the name, without opening a new one.
+func (s system) file(op, name string) (*File, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	switch f := s.files[fsutil.CleanRelative(name)].(type) {
+	case nil:
+		return nil, &fs.PathError{Op: op, Path: name, Err: fs.ErrNotExist}
+	case *File:
+		return f, nil
+	default:
+		return nil, &fs.PathError{Op: op, Path: name, Err: fs.ErrNotSupported}
+	}
+}
+
+func renameError(oldname, newname string, err error) error {
+	return &fs.LinkError{Op: "rename", Old: oldname, New: newname, Err: err}
+}
+
+// Returns the parent directory for the named file. For systems without a root
+// directory entry, nil is returned for files at the top level.
+func (s system) parent(op, name string) (*File, error) {
+	dir := path.Dir(name)
+	f := s.files[dir]
+	if f == nil {
+		if dir == "." {
+			return nil, nil
+		}
+		return nil, &fs.PathError{Op: op, Path: name, Err: fs.ErrNotExist}
+	}
+	d, ok := f.(*File)
+	if !ok || !d.isDir {
+		return nil, &fs.PathError{Op: op, Path: name, Err: fs.ErrNotDir}
+	}
+	return d, nil
+}
+
+// Adds the File to the System, and its info to the parent directory.
+func (s system) add(op, name string, f *File) error {
+	p, err := s.parent(op, name)
+	if err != nil {
+		return err
+	}
+	if p != nil {
+		if err := p.AddDirInfo(f.fileInfo); err != nil {
+			return err
+		}
+		p.entriesChanged()
+	}
+	f.attach(s, name)
+	s.files[name] = f
+	s.watch.notify(name, fs.OpCreate)
+	return nil
+}
+
+// Removes the named file from the System, and its info from the parent
+// directory.
+func (s system) unlink(op, name string) error {
+	p, err := s.parent(op, name)
+	if err != nil {
+		return err
+	}
+	if p != nil {
+		if err := p.RemoveDirInfo(path.Base(name)); err != nil {
+			return err
+		}
+		p.entriesChanged()
+	}
+	if mf, ok := s.files[name].(*File); ok {
+		mf.unwatch()
+	}
+	delete(s.files, name)
+	return nil
+}
+
 // Creates a fs.System backed by the given map. It expects directories to also
-// have provided entries as necessary and won't create them.
+// have provided entries as necessary and won't create them. Keys are expected
+// to be clean slash separated paths like "dir/file", with "." for the root
+// directory. Afterwards the System maintains the directory listings and their
+// timestamps itself as files are created, removed and renamed.
 func NewSystem(files map[string]fs.File) fs.System {
-	return system(files)
+	return NewSystemWithConfig(files, Config{})
+}
+
+// Creates a fs.System backed by the given map like NewSystem, customized by the
+// Config.
+func NewSystemWithConfig(files map[string]fs.File, c Config) fs.System {
+	if files == nil {
+		files = make(map[string]fs.File)
+	}
+	if c.Clock == nil {
+		c.Clock = fs.SystemClock
+	}
+	s := system{
+		mu:    new(sync.RWMutex),
+		files: files,
+		watch: new(watchList),
+		clock: c.Clock,
+	}
+	for name, f := range files {
+		if mf, ok := f.(*File); ok {
+			mf.attach(s, name)
+		}
+	}
+	return s
 }
 
 // Creates a fs.System backed by the given map. It expects only Files and will
-// generate Directory entries automatically.
+// generate Directory entries automatically, including the root directory ".".
 func NewWithFiles(files map[string]fs.File) fs.System {
+	return NewWithFilesAndConfig(files, Config{})
+}
+
+// Creates a fs.System backed by the given map like NewWithFiles, customized by
+// the Config. The generated directories are timestamped using the Clock.
+func NewWithFilesAndConfig(files map[string]fs.File, c Config) fs.System {
+	if c.Clock == nil {
+		c.Clock = fs.SystemClock
+	}
 	s := make(map[string]fs.File)
 	var add func(fullpath string, file fs.File) error
 	add = func(fullpath string, file fs.File) error {
@@ -40,18 +463,21 @@ func NewWithFiles(files map[string]fs.File) fs.System {
 			return err
 		}
 		s[fullpath] = file
-		parent := filepath.Dir(fullpath)
-		if parent == "." {
+		if fullpath == "." {
 			return nil
 		}
+		parent := path.Dir(fullpath)
 		if parentdir := s[parent]; parentdir != nil {
-			pf := parentdir.(*File)
+			pf, ok := parentdir.(*File)
+			if !ok {
+				return &fs.PathError{Op: "open", Path: parent, Err: fs.ErrNotDir}
+			}
 			if err := pf.AddDirInfo(fi); err != nil {
 				return err
 			}
 		} else {
 			parentdir := NewDir(
-				filepath.Base(parent), os.FileMode(755), time.Now(), []os.FileInfo{fi})
+				parent, os.FileMode(0755), c.Clock.Now(), []os.FileInfo{fi})
 			if err := add(parent, parentdir); err != nil {
 				return err
 			}
@@ -59,10 +485,19 @@ func NewWithFiles(files map[string]fs.File) fs.System {
 		return nil
 	}
 
-	for fullpath, file := range files {
-		if err := add(fullpath, file); err != nil {
+	// sorted so parents are always seen before their children
+	names := make([]string, 0, len(files))
+	for fullpath := range files {
+		names = append(names, fullpath)
+	}
+	sort.Strings(names)
+	for _, fullpath := range names {
+		if err := add(fsutil.CleanRelative(fullpath), files[fullpath]); err != nil {
 			return emptyfs.NewWithError(err)
 		}
 	}
-	return NewSystem(s)
+	if s["."] == nil {
+		s["."] = NewDir(".", os.FileMode(0755), c.Clock.Now(), nil)
+	}
+	return NewSystemWithConfig(s, c)
 }
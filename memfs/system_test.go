@@ -1,11 +1,21 @@
 package memfs_test
 
 import (
+	"errors"
+	"io"
+	"io/ioutil"
 	"os"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
 	"testing"
 	"time"
 
 	"github.com/daaku/go.fs"
+	"github.com/daaku/go.fs/fstest"
+	"github.com/daaku/go.fs/fsutil"
 	"github.com/daaku/go.fs/memfs"
 )
 
@@ -19,8 +29,15 @@ func TestSimpleSystem(t *testing.T) {
 	if err != nil {
 		t.Fatal(err)
 	}
-	if openedFile != createdFile {
-		t.Fatal("did not find expected file")
+	if openedFile == createdFile {
+		t.Fatal("was expecting a new handle")
+	}
+	b, err := ioutil.ReadAll(openedFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(b) != data {
+		t.Fatalf("was expecting %s, got %s", data, b)
 	}
 }
 
@@ -48,9 +65,7 @@ func TestSystemWithFiles(t *testing.T) {
 	if err != nil {
 		t.Fatal(err)
 	}
-	if a1 != f1 {
-		t.Fatal("did not find expected file")
-	}
+	assertSameFile(t, a1, f1)
 	d, err := s.Open("d")
 	if err != nil {
 		t.Fatal(err)
@@ -59,9 +74,28 @@ func TestSystemWithFiles(t *testing.T) {
 	if len(some) != 2 {
 		t.Fatal("was expecting 2 names")
 	}
-	if actual := some[1]; actual != "bar" {
+	if actual := some[0]; actual != "bar" {
 		t.Fatal("was expecting bar")
 	}
+	if actual := some[1]; actual != "foo" {
+		t.Fatal("was expecting foo")
+	}
+}
+
+// Asserts the opened File is a new handle for the given File.
+func assertSameFile(t *testing.T, opened fs.File, f *memfs.File) {
+	t.Helper()
+	if opened == fs.File(f) {
+		t.Fatal("was expecting a new handle")
+	}
+	expected, _ := f.Ident()
+	actual, err := opened.Ident()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if actual.Inode != expected.Inode {
+		t.Fatal("did not find expected file")
+	}
 }
 
 func TestSystemWithFilesClosedFile(t *testing.T) {
@@ -103,9 +137,7 @@ func TestSystemWithFilesTwoLevels(t *testing.T) {
 	if err != nil {
 		t.Fatal(err)
 	}
-	if a1 != f1 {
-		t.Fatal("did not find expected file")
-	}
+	assertSameFile(t, a1, f1)
 	d, err := s.Open("d")
 	if err != nil {
 		t.Fatal(err)
@@ -118,3 +150,739 @@ func TestSystemWithFilesTwoLevels(t *testing.T) {
 		t.Fatal("was expecting bar")
 	}
 }
+
+func TestSystemWithFilesRoot(t *testing.T) {
+	t.Parallel()
+	f1 := memfs.NewFile("foo", os.FileMode(666), time.Now(), nil)
+	s := memfs.NewWithFiles(map[string]fs.File{
+		"d/foo": f1,
+	})
+	root, err := s.Open(".")
+	if err != nil {
+		t.Fatal(err)
+	}
+	names, err := root.Readdirnames(0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(names) != 1 || names[0] != "d" {
+		t.Fatalf("was expecting d, got %v", names)
+	}
+}
+
+func TestSystemCreate(t *testing.T) {
+	t.Parallel()
+	s := memfs.NewWithFiles(nil)
+	f, err := s.Create("/foo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := f.WriteString("bar"); err != nil {
+		t.Fatal(err)
+	}
+	if err := f.Close(); err != nil {
+		t.Fatal(err)
+	}
+	f, err = s.Open("foo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := ioutil.ReadAll(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(b) != "bar" {
+		t.Fatalf("was expecting bar, got %s", b)
+	}
+	root, err := s.Open(".")
+	if err != nil {
+		t.Fatal(err)
+	}
+	names, _ := root.Readdirnames(0)
+	if len(names) != 1 || names[0] != "foo" {
+		t.Fatalf("was expecting foo, got %v", names)
+	}
+}
+
+func TestSystemCreateTruncates(t *testing.T) {
+	t.Parallel()
+	s := memfs.NewWithFiles(map[string]fs.File{
+		"foo": memfs.NewFile("foo", os.FileMode(666), time.Now(), []byte("bar")),
+	})
+	f, err := s.Create("foo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	fi, err := f.Stat()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fi.Size() != 0 {
+		t.Fatalf("was expecting 0 size, got %d", fi.Size())
+	}
+}
+
+func TestSystemOpenFileExclusive(t *testing.T) {
+	t.Parallel()
+	s := memfs.NewWithFiles(map[string]fs.File{
+		"foo": memfs.NewFile("foo", os.FileMode(666), time.Now(), nil),
+	})
+	_, err := s.OpenFile("foo", os.O_RDWR|os.O_CREATE|os.O_EXCL, 0666)
+	if err == nil {
+		t.Fatal("was expecting error")
+	}
+	_, err = s.OpenFile("bar", os.O_RDWR, 0666)
+	if !s.IsNotExist(err) {
+		t.Fatalf("was expecting is not exist error, got %v", err)
+	}
+}
+
+func TestSystemCreateMissingParent(t *testing.T) {
+	t.Parallel()
+	s := memfs.NewWithFiles(nil)
+	_, err := s.Create("d/foo")
+	if !s.IsNotExist(err) {
+		t.Fatalf("was expecting is not exist error, got %v", err)
+	}
+}
+
+func TestSystemMkdirAll(t *testing.T) {
+	t.Parallel()
+	s := memfs.NewWithFiles(nil)
+	if err := s.MkdirAll("a/b/c", 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.MkdirAll("a/b", 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Mkdir("a/b", 0755); err == nil {
+		t.Fatal("was expecting error")
+	}
+	d, err := s.Open("a/b")
+	if err != nil {
+		t.Fatal(err)
+	}
+	fi, err := d.Stat()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !fi.IsDir() {
+		t.Fatal("was expecting dir")
+	}
+	names, _ := d.Readdirnames(0)
+	if len(names) != 1 || names[0] != "c" {
+		t.Fatalf("was expecting c, got %v", names)
+	}
+}
+
+func TestSystemRemove(t *testing.T) {
+	t.Parallel()
+	s := memfs.NewWithFiles(map[string]fs.File{
+		"d/foo": memfs.NewFile("foo", os.FileMode(666), time.Now(), nil),
+	})
+	if err := s.Remove("d"); err == nil {
+		t.Fatal("was expecting error removing non empty directory")
+	}
+	if err := s.Remove("d/foo"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.Open("d/foo"); !s.IsNotExist(err) {
+		t.Fatalf("was expecting is not exist error, got %v", err)
+	}
+	if err := s.Remove("d"); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Remove("d"); !s.IsNotExist(err) {
+		t.Fatalf("was expecting is not exist error, got %v", err)
+	}
+}
+
+func TestSystemRemoveAll(t *testing.T) {
+	t.Parallel()
+	s := memfs.NewWithFiles(map[string]fs.File{
+		"d/e/foo": memfs.NewFile("foo", os.FileMode(666), time.Now(), nil),
+		"d/bar":   memfs.NewFile("bar", os.FileMode(666), time.Now(), nil),
+	})
+	f, err := s.OpenFile("d/e/foo", os.O_WRONLY, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	if err := s.RemoveAll("d"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.Open("d/e/foo"); !s.IsNotExist(err) {
+		t.Fatalf("was expecting is not exist error, got %v", err)
+	}
+	if err := s.RemoveAll("d"); err != nil {
+		t.Fatal(err)
+	}
+
+	// the open File keeps working, but is no longer watched
+	w, err := fsutil.Watch(s, ".", true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := f.Write([]byte("foo")); err != nil {
+		t.Fatal(err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	for ev := range w.Events() {
+		t.Fatalf("was expecting no events, got %s", ev)
+	}
+}
+
+func TestSystemRename(t *testing.T) {
+	t.Parallel()
+	f1 := memfs.NewFile("foo", os.FileMode(666), time.Now(), nil)
+	s := memfs.NewWithFiles(map[string]fs.File{
+		"d/e/foo": f1,
+	})
+	if err := s.Mkdir("x", 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Rename("d/e", "x/y"); err != nil {
+		t.Fatal(err)
+	}
+	a1, err := s.Open("x/y/foo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	assertSameFile(t, a1, f1)
+	if f1.Name() != "x/y/foo" {
+		t.Fatalf("did not find expected name, got %s", f1.Name())
+	}
+	if _, err := s.Open("d/e/foo"); !s.IsNotExist(err) {
+		t.Fatalf("was expecting is not exist error, got %v", err)
+	}
+	d, err := s.Open("d")
+	if err != nil {
+		t.Fatal(err)
+	}
+	names, _ := d.Readdirnames(0)
+	if len(names) != 0 {
+		t.Fatalf("was expecting empty dir, got %v", names)
+	}
+	if err := s.Rename("x", "x/z"); err == nil {
+		t.Fatal("was expecting error moving directory into itself")
+	}
+}
+
+func TestSystemRenameNotEmpty(t *testing.T) {
+	t.Parallel()
+	s := memfs.NewWithFiles(map[string]fs.File{
+		"d/foo": memfs.NewFile("foo", os.FileMode(666), time.Now(), nil),
+		"e/bar": memfs.NewFile("bar", os.FileMode(666), time.Now(), nil),
+	})
+	err := s.Rename("d", "e")
+	var le *fs.LinkError
+	if !errors.As(err, &le) || le.Op != "rename" || !errors.Is(err, fs.ErrNotEmpty) {
+		t.Fatalf("was expecting a rename LinkError with ErrNotEmpty, got %#v", err)
+	}
+}
+
+func TestSystemStat(t *testing.T) {
+	t.Parallel()
+	f1 := memfs.NewFile("foo", os.FileMode(666), time.Now(), []byte("bar"))
+	s := memfs.NewWithFiles(map[string]fs.File{
+		"d/foo": f1,
+	})
+	f1.Close()
+	fi, err := fsutil.Stat(s, "d/foo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fi.Size() != 3 {
+		t.Fatal("did not find expected size")
+	}
+	fi, err = fsutil.Stat(s, "d")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !fi.IsDir() {
+		t.Fatal("was expecting dir")
+	}
+	if _, err := fsutil.Stat(s, "d/bar"); !s.IsNotExist(err) {
+		t.Fatalf("was expecting is not exist error, got %v", err)
+	}
+}
+
+func TestSystemReadFile(t *testing.T) {
+	t.Parallel()
+	f1 := memfs.NewFile("foo", os.FileMode(666), time.Now(), []byte("bar"))
+	s := memfs.NewWithFiles(map[string]fs.File{
+		"d/foo": f1,
+	})
+	if _, err := f1.Seek(1, os.SEEK_SET); err != nil {
+		t.Fatal(err)
+	}
+	b, err := fsutil.ReadFile(s, "d/foo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(b) != "bar" {
+		t.Fatalf("was expecting bar, got %s", b)
+	}
+	b[0] = 'c'
+	if off, _ := f1.Seek(0, os.SEEK_CUR); off != 1 {
+		t.Fatalf("was expecting offset to be untouched, got %d", off)
+	}
+	b, err = fsutil.ReadFile(s, "d/foo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(b) != "bar" {
+		t.Fatalf("was expecting a copy of the data, got %s", b)
+	}
+	if _, err := fsutil.ReadFile(s, "d"); !errors.Is(err, fs.ErrIsDir) {
+		t.Fatalf("was expecting ErrIsDir, got %v", err)
+	}
+}
+
+func TestConformance(t *testing.T) {
+	t.Parallel()
+	fstest.TestSystem(t, fstest.Config{
+		New: func(t *testing.T, files map[string]string) (fs.System, string) {
+			mfiles := make(map[string]fs.File)
+			for name, data := range files {
+				mfiles[name] = memfs.NewFile(name, 0644, time.Now(), []byte(data))
+			}
+			return memfs.NewWithFiles(mfiles), "."
+		},
+	})
+}
+
+func TestSystemWatch(t *testing.T) {
+	t.Parallel()
+	s := memfs.NewWithFiles(map[string]fs.File{
+		"d/foo":   memfs.NewFile("foo", 0644, time.Now(), nil),
+		"d/e/bar": memfs.NewFile("bar", 0644, time.Now(), nil),
+	})
+	w, err := fsutil.Watch(s, "/d", false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	f, err := s.OpenFile("d/foo", os.O_WRONLY, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := f.Write([]byte("a")); err != nil {
+		t.Fatal(err)
+	}
+	if err := fsutil.WriteFile(s, "d/e/bar", nil, 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Rename("d/foo", "d/baz"); err != nil {
+		t.Fatal(err)
+	}
+	if err := fsutil.Chmod(s, "d/baz", 0600); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Remove("d/baz"); err != nil {
+		t.Fatal(err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	var events []string
+	for ev := range w.Events() {
+		events = append(events, ev.String())
+	}
+	expected := []string{
+		"WRITE /d/foo",
+		"RENAME /d/foo",
+		"CREATE /d/baz",
+		"CHMOD /d/baz",
+		"REMOVE /d/baz",
+	}
+	if !reflect.DeepEqual(events, expected) {
+		t.Fatalf("was expecting %v, got %v", expected, events)
+	}
+	if _, err := fsutil.Watch(s, "missing", true); !s.IsNotExist(err) {
+		t.Fatalf("was expecting is not exist error, got %v", err)
+	}
+}
+
+func TestSystemWatchRecursive(t *testing.T) {
+	t.Parallel()
+	s := memfs.NewWithFiles(nil)
+	w, err := fsutil.Watch(s, ".", true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer w.Close()
+	if err := s.MkdirAll("a/b", 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := fsutil.WriteFile(s, "a/b/c", nil, 0644); err != nil {
+		t.Fatal(err)
+	}
+	for _, expected := range []string{"CREATE a", "CREATE a/b", "CREATE a/b/c"} {
+		if ev := <-w.Events(); ev.String() != expected {
+			t.Fatalf("was expecting %s, got %s", expected, ev)
+		}
+	}
+}
+
+func TestSystemChtimes(t *testing.T) {
+	t.Parallel()
+	s := memfs.NewWithFiles(map[string]fs.File{
+		"d/foo": memfs.NewFile("foo", 0644, time.Now(), nil),
+	})
+	atime := time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC)
+	mtime := atime.Add(time.Hour)
+	if err := fsutil.Chtimes(s, "d/foo", atime, mtime); err != nil {
+		t.Fatal(err)
+	}
+	times, err := fsutil.Times(s, "d/foo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !times.Atime.Equal(atime) || !times.Mtime.Equal(mtime) {
+		t.Fatalf("did not find expected times, got %v", times)
+	}
+	if err := fsutil.Chtimes(s, "d/missing", atime, mtime); !s.IsNotExist(err) {
+		t.Fatalf("was expecting is not exist error, got %v", err)
+	}
+}
+
+func TestSystemClock(t *testing.T) {
+	t.Parallel()
+	start := time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC)
+	clock := fstest.NewFakeClock(start)
+	s := memfs.NewWithFilesAndConfig(map[string]fs.File{
+		"d/foo": memfs.NewFile("foo", 0644, start, nil),
+	}, memfs.Config{Clock: clock})
+	assertTimes := func(name string, mtime, ctime time.Time) {
+		t.Helper()
+		times, err := fsutil.Times(s, name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !times.Mtime.Equal(mtime) || !times.Ctime.Equal(ctime) {
+			t.Fatalf("%s: was expecting mtime %s and ctime %s, got %v",
+				name, mtime, ctime, times)
+		}
+	}
+	assertTimes(".", start, start)
+	assertTimes("d", start, start)
+
+	created := clock.Advance(time.Minute)
+	if err := fsutil.WriteFile(s, "d/bar", nil, 0644); err != nil {
+		t.Fatal(err)
+	}
+	assertTimes("d/bar", created, created)
+	assertTimes("d", created, created)
+
+	written := clock.Advance(time.Minute)
+	if err := fsutil.WriteFile(s, "d/foo", []byte("foo"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	assertTimes("d/foo", written, written)
+
+	truncated := clock.Advance(time.Minute)
+	if err := fsutil.Truncate(s, "d/foo", 1); err != nil {
+		t.Fatal(err)
+	}
+	assertTimes("d/foo", truncated, truncated)
+
+	chmodded := clock.Advance(time.Minute)
+	if err := fsutil.Chmod(s, "d/foo", 0600); err != nil {
+		t.Fatal(err)
+	}
+	assertTimes("d/foo", truncated, chmodded)
+	assertTimes("d", created, created)
+
+	// clones keep using the Clock
+	c, err := memfs.Clone(s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cloned := clock.Advance(time.Minute)
+	if err := c.Mkdir("e", 0755); err != nil {
+		t.Fatal(err)
+	}
+	times, err := fsutil.Times(c, "e")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !times.Mtime.Equal(cloned) {
+		t.Fatalf("was expecting mtime %s, got %s", cloned, times.Mtime)
+	}
+}
+
+func TestSystemOpenHandles(t *testing.T) {
+	t.Parallel()
+	f1 := memfs.NewFile("foo", os.FileMode(666), time.Now(), []byte("bar"))
+	s := memfs.NewWithFiles(map[string]fs.File{
+		"foo": f1,
+	})
+	a1, err := s.Open("foo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	a2, err := s.Open("foo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if a1 == a2 {
+		t.Fatal("was expecting distinct handles")
+	}
+	b := make([]byte, 2)
+	if _, err := a1.Read(b); err != nil {
+		t.Fatal(err)
+	}
+	if err := a1.Close(); err != nil {
+		t.Fatal(err)
+	}
+	b, err = ioutil.ReadAll(a2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(b) != "bar" {
+		t.Fatalf("was expecting bar, got %s", b)
+	}
+	if _, err := f1.WriteString("baz"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := a2.Seek(0, os.SEEK_SET); err != nil {
+		t.Fatal(err)
+	}
+	b, err = ioutil.ReadAll(a2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(b) != "baz" {
+		t.Fatalf("was expecting baz, got %s", b)
+	}
+}
+
+func TestSystemLockContention(t *testing.T) {
+	t.Parallel()
+	s := memfs.NewWithFiles(map[string]fs.File{
+		"foo": memfs.NewFile("foo", os.FileMode(666), time.Now(), nil),
+	})
+	a1, err := s.Open("foo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	a2, err := s.Open("foo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := fsutil.RLock(a1); err != nil {
+		t.Fatal(err)
+	}
+	if err := fsutil.RLock(a2); err != nil {
+		t.Fatal(err)
+	}
+	if locked, _ := fsutil.TryLock(a2); locked {
+		t.Fatal("was expecting the shared lock to block the exclusive one")
+	}
+	if err := fsutil.Unlock(a2); err != nil {
+		t.Fatal(err)
+	}
+
+	// a2 waits until a1 is closed
+	acquired := make(chan error)
+	go func() { acquired <- fsutil.Lock(a2) }()
+	select {
+	case err := <-acquired:
+		t.Fatalf("was expecting Lock to block, got %v", err)
+	case <-time.After(10 * time.Millisecond):
+	}
+	if err := a1.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err := <-acquired; err != nil {
+		t.Fatal(err)
+	}
+	if locked, _ := fsutil.TryLock(a1); locked {
+		t.Fatal("was expecting the closed handle to fail")
+	}
+}
+
+func TestSystemConcurrent(t *testing.T) {
+	t.Parallel()
+	s := memfs.NewWithFiles(map[string]fs.File{
+		"d/foo": memfs.NewFile("foo", os.FileMode(666), time.Now(), []byte("foo")),
+	})
+	w, err := fsutil.Watch(s, ".", true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer w.Close()
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			dir := "d/" + strconv.Itoa(i)
+			for j := 0; j < 50; j++ {
+				if _, err := fsutil.ReadFile(s, "d/foo"); err != nil {
+					t.Error(err)
+					return
+				}
+				f, err := s.OpenFile("d/foo", os.O_RDWR, 0)
+				if err != nil {
+					t.Error(err)
+					return
+				}
+				if _, err := f.WriteAt([]byte("bar"), 0); err != nil {
+					t.Error(err)
+				}
+				if _, err := ioutil.ReadAll(f); err != nil {
+					t.Error(err)
+				}
+				f.Close()
+				if err := s.MkdirAll(dir+"/e", 0755); err != nil {
+					t.Error(err)
+					return
+				}
+				if err := fsutil.WriteFile(s, dir+"/e/bar", []byte("bar"), 0644); err != nil {
+					t.Error(err)
+					return
+				}
+				if _, err := fsutil.ReadDir(s, "d"); err != nil {
+					t.Error(err)
+					return
+				}
+				if _, err := fsutil.Stat(s, dir+"/e/bar"); err != nil {
+					t.Error(err)
+					return
+				}
+				if err := s.Rename(dir, dir+"x"); err != nil {
+					t.Error(err)
+					return
+				}
+				if err := s.RemoveAll(dir + "x"); err != nil {
+					t.Error(err)
+					return
+				}
+			}
+		}(i)
+	}
+	wg.Wait()
+	names, err := fsutil.ReadDir(s, "d")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(names) != 1 || names[0].Name() != "foo" {
+		t.Fatalf("was expecting only foo, got %v", names)
+	}
+}
+
+func TestSystemDirectoryBookkeeping(t *testing.T) {
+	t.Parallel()
+	past := time.Now().Add(-time.Hour)
+	s := memfs.NewWithFiles(map[string]fs.File{
+		"d/foo": memfs.NewFile("foo", os.FileMode(666), past, nil),
+	})
+	assertModified := func(name string, op func() error) {
+		t.Helper()
+		if err := fsutil.Chtimes(s, name, past, past); err != nil {
+			t.Fatal(err)
+		}
+		if err := op(); err != nil {
+			t.Fatal(err)
+		}
+		fi, err := fsutil.Stat(s, name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !fi.ModTime().After(past) {
+			t.Fatalf("was expecting %s to be modified", name)
+		}
+	}
+	assertModified("d", func() error {
+		return fsutil.WriteFile(s, "d/bar", nil, 0644)
+	})
+	assertModified("d", func() error { return s.Mkdir("d/e", 0755) })
+	assertModified("d", func() error { return s.Remove("d/bar") })
+	assertModified(".", func() error { return s.Rename("d/foo", "foo") })
+	assertModified("d/e", func() error { return s.Rename("foo", "d/e/foo") })
+
+	if err := s.Remove("d/e"); !errors.Is(err, fs.ErrNotEmpty) {
+		t.Fatalf("was expecting ErrNotEmpty, got %v", err)
+	}
+	if err := s.Remove("."); !errors.Is(err, fs.ErrInvalid) {
+		t.Fatalf("was expecting ErrInvalid, got %v", err)
+	}
+	if err := s.Rename("d/e", "d/f"); err != nil {
+		t.Fatal(err)
+	}
+	names, err := fsutil.ReadDir(s, "d/f")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(names) != 1 || names[0].Name() != "foo" {
+		t.Fatalf("was expecting foo, got %v", names)
+	}
+	if err := fsutil.WriteFile(s, "d/f/foo/bar", nil, 0644); !errors.Is(err, fs.ErrNotDir) {
+		t.Fatalf("was expecting ErrNotDir, got %v", err)
+	}
+
+	// the root is kept, but emptied
+	if err := s.RemoveAll("."); err != nil {
+		t.Fatal(err)
+	}
+	names, err = fsutil.ReadDir(s, ".")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(names) != 0 {
+		t.Fatalf("was expecting empty root, got %v", names)
+	}
+	if _, err := fsutil.Stat(s, "d/f"); !s.IsNotExist(err) {
+		t.Fatalf("was expecting is not exist error, got %v", err)
+	}
+}
+
+func TestSystemOpenAccessMode(t *testing.T) {
+	t.Parallel()
+	s := memfs.NewWithFiles(map[string]fs.File{
+		"foo": memfs.NewFile("foo", os.FileMode(666), time.Now(), []byte("foo")),
+	})
+	r, err := s.Open("foo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := r.Write([]byte("bar")); !errors.Is(err, syscall.EBADF) {
+		t.Fatalf("was expecting EBADF, got %v", err)
+	}
+	if err := r.Truncate(0); !errors.Is(err, fs.ErrInvalid) {
+		t.Fatalf("was expecting ErrInvalid, got %v", err)
+	}
+	src := strings.NewReader("bar")
+	if _, err := r.(io.ReaderFrom).ReadFrom(src); !errors.Is(err, syscall.EBADF) {
+		t.Fatalf("was expecting EBADF, got %v", err)
+	}
+	if src.Len() != 3 {
+		t.Fatalf("was expecting nothing to be read, got %d left", src.Len())
+	}
+	w, err := s.OpenFile("foo", os.O_WRONLY|os.O_APPEND, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := w.Read(make([]byte, 1)); !errors.Is(err, syscall.EBADF) {
+		t.Fatalf("was expecting EBADF, got %v", err)
+	}
+	if _, err := w.WriteAt([]byte("bar"), 0); !errors.Is(err, fs.ErrInvalid) {
+		t.Fatalf("was expecting ErrInvalid, got %v", err)
+	}
+	if _, err := w.Seek(0, os.SEEK_SET); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := w.Write([]byte("bar")); err != nil {
+		t.Fatal(err)
+	}
+	b, err := ioutil.ReadAll(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(b) != "foobar" {
+		t.Fatalf("was expecting appended writes, got %s", b)
+	}
+}
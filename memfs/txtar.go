@@ -0,0 +1,86 @@
+package memfs
+
+import (
+	"bytes"
+	"strings"
+)
+
+// The txtar format is described in golang.org/x/tools/txtar. It is
+// implemented here to avoid the dependency, and only the files are kept since
+// the leading comment has no place in a System.
+
+// A file in a txtar archive.
+type txtarFile struct {
+	name string
+	data []byte
+}
+
+var (
+	txtarMarkerStart   = []byte("-- ")
+	txtarMarkerEnd     = []byte(" --")
+	txtarNewlineMarker = []byte("\n-- ")
+)
+
+// Parses the files in the archive, ignoring the leading comment.
+func parseTxtar(data []byte) []txtarFile {
+	var files []txtarFile
+	_, name, data := findTxtarMarker(data)
+	for name != "" {
+		f := txtarFile{name: name}
+		f.data, name, data = findTxtarMarker(data)
+		files = append(files, f)
+	}
+	return files
+}
+
+// Finds the next marker line in data, returning the data before it, the name
+// in it and the data after it. If there is no marker, name is empty and all of
+// data is returned as before.
+func findTxtarMarker(data []byte) (before []byte, name string, after []byte) {
+	var i int
+	for {
+		if name, after = txtarMarker(data[i:]); name != "" {
+			return data[:i], name, after
+		}
+		j := bytes.Index(data[i:], txtarNewlineMarker)
+		if j < 0 {
+			return fixNewline(data), "", nil
+		}
+		i += j + 1
+	}
+}
+
+// Returns the name if data starts with a marker line, along with the data
+// after the line.
+func txtarMarker(data []byte) (name string, after []byte) {
+	if !bytes.HasPrefix(data, txtarMarkerStart) {
+		return "", nil
+	}
+	if i := bytes.IndexByte(data, '\n'); i >= 0 {
+		data, after = data[:i], data[i+1:]
+	}
+	if !bytes.HasSuffix(data, txtarMarkerEnd) ||
+		len(data) < len(txtarMarkerStart)+len(txtarMarkerEnd) {
+		return "", nil
+	}
+	name = string(data[len(txtarMarkerStart) : len(data)-len(txtarMarkerEnd)])
+	return strings.TrimSpace(name), after
+}
+
+// Formats the files as an archive.
+func formatTxtar(files []txtarFile) []byte {
+	var buf bytes.Buffer
+	for _, f := range files {
+		buf.WriteString("-- " + f.name + " --\n")
+		buf.Write(fixNewline(f.data))
+	}
+	return buf.Bytes()
+}
+
+// Returns data ending with a newline, unless it is empty.
+func fixNewline(data []byte) []byte {
+	if len(data) == 0 || data[len(data)-1] == '\n' {
+		return data
+	}
+	return append(data[:len(data):len(data)], '\n')
+}
@@ -0,0 +1,113 @@
+package memfs
+
+import (
+	"path"
+	"strings"
+	"sync"
+
+	"github.com/daaku/go.fs"
+	"github.com/daaku/go.fs/fsutil"
+)
+
+// The number of Events buffered per Watcher. Since changes are reported
+// synchronously by the operation making them, Events are dropped instead of
+// blocking when the buffer is full, and fs.ErrEventOverflow is reported.
+const watchBuffer = 1024
+
+// Watch reports changes made through the System and its Files.
+func (s system) Watch(name string, recursive bool) (fs.Watcher, error) {
+	key := fsutil.CleanRelative(name)
+	s.mu.RLock()
+	f := s.files[key]
+	s.mu.RUnlock()
+	if f == nil {
+		return nil, &fs.PathError{Op: "watch", Path: name, Err: fs.ErrNotExist}
+	}
+	w := &watcher{
+		list:      s.watch,
+		key:       key,
+		name:      name,
+		recursive: recursive,
+		events:    make(chan fs.Event, watchBuffer),
+		errors:    make(chan error, 1),
+	}
+	s.watch.mu.Lock()
+	s.watch.watchers = append(s.watch.watchers, w)
+	s.watch.mu.Unlock()
+	return w, nil
+}
+
+// The Watchers for a System, shared by the System and its Files.
+type watchList struct {
+	mu       sync.Mutex
+	watchers []*watcher
+}
+
+func (l *watchList) notify(key string, op fs.Op) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	for _, w := range l.watchers {
+		w.notify(key, op)
+	}
+}
+
+type watcher struct {
+	list      *watchList
+	key       string // clean name in the System
+	name      string // as given to Watch
+	recursive bool
+	events    chan fs.Event
+	errors    chan error
+}
+
+func (w *watcher) Events() <-chan fs.Event {
+	return w.events
+}
+
+func (w *watcher) Errors() <-chan error {
+	return w.errors
+}
+
+func (w *watcher) Close() error {
+	w.list.mu.Lock()
+	defer w.list.mu.Unlock()
+	for ix, other := range w.list.watchers {
+		if other == w {
+			w.list.watchers = append(w.list.watchers[:ix:ix], w.list.watchers[ix+1:]...)
+			close(w.events)
+			close(w.errors)
+			break
+		}
+	}
+	return nil
+}
+
+// Delivers the Event if the file is being watched. Must be called with the
+// list locked.
+func (w *watcher) notify(key string, op fs.Op) {
+	var rel string
+	switch {
+	case key == w.key:
+	case w.key == ".":
+		rel = key
+	case strings.HasPrefix(key, w.key+"/"):
+		rel = key[len(w.key)+1:]
+	default:
+		return
+	}
+	if !w.recursive && strings.Contains(rel, "/") {
+		return
+	}
+	name := w.name
+	if rel != "" {
+		name = path.Join(w.name, rel)
+	}
+	select {
+	case w.events <- fs.Event{Name: name, Op: op}:
+	default:
+		select {
+		case w.errors <- fs.ErrEventOverflow:
+		default:
+		}
+	}
+}
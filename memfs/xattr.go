@@ -0,0 +1,135 @@
+package memfs
+
+import (
+	"sort"
+
+	"github.com/daaku/go.fs"
+)
+
+// WithXattrs sets the given extended attributes on the File and returns it,
+// which allows seeding them when creating Files for a System.
+func (f *File) WithXattrs(attrs map[string][]byte) *File {
+	f.node.mu.Lock()
+	defer f.node.mu.Unlock()
+	for attr, data := range attrs {
+		f.storexattr(attr, data)
+	}
+	return f
+}
+
+// Getxattr returns a copy of the value of the extended attribute.
+func (f *File) Getxattr(attr string) ([]byte, error) {
+	if f.IsClosed() {
+		return nil, f.pathError("getxattr", fs.ErrClosed)
+	}
+	return f.getxattr(attr)
+}
+
+// Setxattr sets the value of the extended attribute to a copy of data.
+func (f *File) Setxattr(attr string, data []byte) error {
+	if f.IsClosed() {
+		return f.pathError("setxattr", fs.ErrClosed)
+	}
+	return f.setxattr(attr, data)
+}
+
+// Listxattr returns the sorted names of the extended attributes.
+func (f *File) Listxattr() ([]string, error) {
+	if f.IsClosed() {
+		return nil, f.pathError("listxattr", fs.ErrClosed)
+	}
+	return f.listxattr(), nil
+}
+
+// Removexattr removes the extended attribute.
+func (f *File) Removexattr(attr string) error {
+	if f.IsClosed() {
+		return f.pathError("removexattr", fs.ErrClosed)
+	}
+	return f.removexattr(attr)
+}
+
+// The xattr methods without requiring the File to be open, used by the System.
+
+func (f *File) getxattr(attr string) ([]byte, error) {
+	f.node.mu.RLock()
+	defer f.node.mu.RUnlock()
+	data, ok := f.xattrs[attr]
+	if !ok {
+		return nil, f.pathError("getxattr", fs.ErrNoAttr)
+	}
+	return append([]byte{}, data...), nil
+}
+
+func (f *File) setxattr(attr string, data []byte) error {
+	if attr == "" {
+		return f.pathError("setxattr", fs.ErrInvalid)
+	}
+	f.node.mu.Lock()
+	defer f.node.mu.Unlock()
+	f.storexattr(attr, data)
+	f.changed(fs.OpChmod)
+	return nil
+}
+
+func (f *File) listxattr() []string {
+	f.node.mu.RLock()
+	defer f.node.mu.RUnlock()
+	var attrs []string
+	for attr := range f.xattrs {
+		attrs = append(attrs, attr)
+	}
+	sort.Strings(attrs)
+	return attrs
+}
+
+func (f *File) removexattr(attr string) error {
+	f.node.mu.Lock()
+	defer f.node.mu.Unlock()
+	if _, ok := f.xattrs[attr]; !ok {
+		return f.pathError("removexattr", fs.ErrNoAttr)
+	}
+	delete(f.xattrs, attr)
+	f.changed(fs.OpChmod)
+	return nil
+}
+
+// Stores a copy of data. Must be called with the node locked.
+func (f *File) storexattr(attr string, data []byte) {
+	if f.xattrs == nil {
+		f.xattrs = make(map[string][]byte)
+	}
+	f.xattrs[attr] = append([]byte{}, data...)
+}
+
+func (s system) Getxattr(name, attr string) ([]byte, error) {
+	f, err := s.file("getxattr", name)
+	if err != nil {
+		return nil, err
+	}
+	return f.getxattr(attr)
+}
+
+func (s system) Setxattr(name, attr string, data []byte) error {
+	f, err := s.file("setxattr", name)
+	if err != nil {
+		return err
+	}
+	return f.setxattr(attr, data)
+}
+
+func (s system) Listxattr(name string) ([]string, error) {
+	f, err := s.file("listxattr", name)
+	if err != nil {
+		return nil, err
+	}
+	return f.listxattr(), nil
+}
+
+func (s system) Removexattr(name, attr string) error {
+	f, err := s.file("removexattr", name)
+	if err != nil {
+		return err
+	}
+	return f.removexattr(attr)
+}
@@ -4,6 +4,7 @@ import (
 	"archive/zip"
 	"fmt"
 	"github.com/daaku/go.deepimports"
+	"github.com/daaku/go.fs/fsutil"
 	"github.com/daaku/go.fs/pkgfs"
 	"github.com/daaku/go.literalfinder"
 	"io"
@@ -80,21 +81,22 @@ func (b *Build) addResource(ru *pkgfs.Config) error {
 	}
 	b.processed[ru.ImportPath] = true
 	fs := pkgfs.New(*ru)
-	root, err := fs.Open("/")
-	if err != nil {
-		return err
-	}
-	files, err := root.Readdir(0)
-	if err != nil {
-		return err
-	}
-	for _, info := range files {
-		zabs := filepath.Join(ru.ImportPath, info.Name())
+	return fsutil.Walk(fs, "/", func(name string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if info.IsDir() {
+			if name != "/" && !ru.Recursive {
+				return fsutil.SkipDir
+			}
+			return nil
+		}
+		zabs := filepath.Join(ru.ImportPath, name)
 		if info.Mode()&os.ModeType != 0 {
 			if b.Verbose {
 				fmt.Printf("Skipped Resource: %s\n", zabs)
 			}
-			continue
+			return nil
 		}
 		if b.Verbose {
 			fmt.Printf("Resource: %s\n", zabs)
@@ -108,13 +110,12 @@ func (b *Build) addResource(ru *pkgfs.Config) error {
 		if err != nil {
 			return err
 		}
-		r, err := fs.Open(info.Name())
+		r, err := fs.Open(name)
 		if err != nil {
 			return err
 		}
-		if _, err = io.Copy(f, r); err != nil {
-			return err
-		}
-	}
-	return nil
+		defer r.Close()
+		_, err = io.Copy(f, r)
+		return err
+	})
 }
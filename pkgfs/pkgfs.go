@@ -27,31 +27,31 @@ import (
 type Config struct {
 	ImportPath string // the import path to use as the root of the File System
 	Recursive  bool   // default is not recursive
-	Glob       string // optionally limit by a glob pattern
+	Glob       string // optionally limit by a pattern, see fsutil.Match
 }
 
 // Provides scoped access to a package as a File System. If the currently
 // running binary has a zip attached, it will be used, otherwise the GOPATH
 // will be used to find the actual files.
 func New(c Config) fs.System {
-	lc := limitfs.Config{
-		Recursive: c.Recursive,
-		Glob:      c.Glob,
+	if exeZipFS != nil {
+		return newSystem(c, exeZipFS, c.ImportPath)
 	}
-	var s fs.System
-	if exeZipFS == nil {
-		s = realfs.New()
-		pkg, err := build.Import(c.ImportPath, "", build.FindOnly)
-		if err != nil {
-			s = emptyfs.NewWithError(err)
-		} else {
-			lc.Root = pkg.Dir
-		}
-	} else {
-		s = exeZipFS
-		lc.Root = c.ImportPath
+	pkg, err := build.Import(c.ImportPath, "", build.FindOnly)
+	if err != nil {
+		return newSystem(c, emptyfs.NewWithError(err), "")
 	}
-	return limitfs.New(lc, s)
+	return newSystem(c, realfs.New(), pkg.Dir)
+}
+
+// Provides the read-only view of the root directory in the System.
+func newSystem(c Config, s fs.System, root string) fs.System {
+	return limitfs.New(limitfs.Config{
+		Root:      root,
+		Recursive: c.Recursive,
+		Glob:      c.Glob,
+		ReadOnly:  true,
+	}, s)
 }
 
 // Returns true if the currently running executable was found to have an
@@ -1,21 +1,139 @@
 package pkgfs
 
 import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"syscall"
 	"testing"
+
+	"github.com/daaku/go.fs"
+	"github.com/daaku/go.fs/fstest"
+	"github.com/daaku/go.fs/fsutil"
+	"github.com/daaku/go.fs/realfs"
 )
 
-// /file
-// /dir/file
-// /dir : /dir/file1 /dir/file2
-// missing prefix slash
-// ../file
-// / with glob
-// empty dir
-// subdir with glob with real files but not matching the glob
-// empty string
-// non file/dir (like socket/pipe)
-//
-
-func TestStub(t *testing.T) {
+// Creates a package directory with the fstest.Files, along with a file
+// outside of it.
+func newPackageDir(t *testing.T) string {
+	dir, err := ioutil.TempDir("", "pkgfs_test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.RemoveAll(dir) })
+	if err := fstest.Populate(realfs.New(), filepath.Join(dir, "pkg"), fstest.Files); err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(filepath.Join(dir, "outside.txt"), nil, 0644); err != nil {
+		t.Fatal(err)
+	}
+	return filepath.Join(dir, "pkg")
+}
+
+func TestConformance(t *testing.T) {
+	t.Parallel()
+	fstest.TestSystem(t, fstest.Config{
+		New: func(t *testing.T, files map[string]string) (fs.System, string) {
+			return newSystem(Config{Recursive: true}, realfs.New(), newPackageDir(t)), "/"
+		},
+		ReadOnly: true,
+	})
+}
+
+func TestNames(t *testing.T) {
+	t.Parallel()
+	s := newSystem(Config{Recursive: true}, realfs.New(), newPackageDir(t))
+	for _, name := range []string{"/foo.txt", "foo.txt", "../foo.txt", "/d/../foo.txt"} {
+		b, err := fsutil.ReadFile(s, name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(b) != "foo" {
+			t.Fatalf("%s: was expecting foo, got %s", name, b)
+		}
+	}
+	for _, name := range []string{"", "/", "."} {
+		fi, err := fsutil.Stat(s, name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !fi.IsDir() {
+			t.Fatalf("%q: was expecting the root directory", name)
+		}
+	}
+	if _, err := s.Open("../outside.txt"); !s.IsNotExist(err) {
+		t.Fatalf("was expecting is not exist error, got %v", err)
+	}
+}
+
+func TestNotRecursive(t *testing.T) {
+	t.Parallel()
+	s := newSystem(Config{}, realfs.New(), newPackageDir(t))
+	if _, err := s.Open("/foo.txt"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.Open("/d/bar.txt"); !s.IsNotExist(err) {
+		t.Fatalf("was expecting is not exist error, got %v", err)
+	}
+}
+
+func TestGlob(t *testing.T) {
+	t.Parallel()
+	dir := newPackageDir(t)
+	if err := ioutil.WriteFile(filepath.Join(dir, "d", "e", "f.html"), nil, 0644); err != nil {
+		t.Fatal(err)
+	}
+	s := newSystem(Config{Recursive: true, Glob: "**/d/*.txt"}, realfs.New(), dir)
+	names, err := fsutil.Glob(s, "/**")
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := []string{"/", "/d", "/d/bar.txt", "/d/baz.txt", "/d/e"}
+	if !reflect.DeepEqual(names, expected) {
+		t.Fatalf("was expecting %v, got %v", expected, names)
+	}
+	if _, err := s.Open("/d/e/qux.txt"); !s.IsNotExist(err) {
+		t.Fatalf("was expecting is not exist error, got %v", err)
+	}
+}
+
+func TestEmptyDir(t *testing.T) {
+	t.Parallel()
+	dir := newPackageDir(t)
+	if err := os.Mkdir(filepath.Join(dir, "empty"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	s := newSystem(Config{Recursive: true}, realfs.New(), dir)
+	infos, err := fsutil.ReadDir(s, "/empty")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(infos) != 0 {
+		t.Fatalf("was expecting no entries, got %v", infos)
+	}
+}
+
+func TestSpecialFile(t *testing.T) {
+	t.Parallel()
+	dir := newPackageDir(t)
+	if err := syscall.Mkfifo(filepath.Join(dir, "fifo"), 0644); err != nil {
+		t.Skip(err)
+	}
+	s := newSystem(Config{Recursive: true}, realfs.New(), dir)
+	fi, err := fsutil.Stat(s, "/fifo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fi.Mode()&os.ModeNamedPipe == 0 {
+		t.Fatalf("was expecting a named pipe, got %v", fi.Mode())
+	}
+}
+
+func TestMissingPackage(t *testing.T) {
 	t.Parallel()
+	s := New(Config{ImportPath: "github.com/daaku/go.fs/pkgfs/missing"})
+	if _, err := s.Open("/foo.txt"); err == nil {
+		t.Fatal("was expecting an error")
+	}
 }
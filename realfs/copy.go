@@ -0,0 +1,69 @@
+package realfs
+
+import (
+	"io"
+)
+
+// ReadFrom unwraps other realfs Files, allowing the kernel to copy the data
+// without it passing through userspace. Copying an entire file into an empty
+// one uses a reflink if the file system supports it, and otherwise
+// copy_file_range or sendfile are used where available.
+func (f file) ReadFrom(r io.Reader) (int64, error) {
+	switch src := r.(type) {
+	case file:
+		if n, ok := f.clone(src); ok {
+			return n, nil
+		}
+		return f.File.ReadFrom(src.File)
+	case *io.LimitedReader:
+		if inner, ok := src.R.(file); ok {
+			lr := &io.LimitedReader{R: inner.File, N: src.N}
+			n, err := f.File.ReadFrom(lr)
+			src.N = lr.N
+			return n, err
+		}
+	}
+	return f.File.ReadFrom(r)
+}
+
+// WriteTo uses ReadFrom when writing to another realfs File.
+func (f file) WriteTo(w io.Writer) (int64, error) {
+	if dst, ok := w.(file); ok {
+		return dst.ReadFrom(f)
+	}
+	return f.File.WriteTo(w)
+}
+
+// Clones the entire src into f if both are at the start, f is empty, and the
+// file system supports it. Returns false if nothing was done.
+func (f file) clone(src file) (int64, bool) {
+	srcOff, err := src.Seek(0, io.SeekCurrent)
+	if err != nil || srcOff != 0 {
+		return 0, false
+	}
+	dstOff, err := f.Seek(0, io.SeekCurrent)
+	if err != nil || dstOff != 0 {
+		return 0, false
+	}
+	srcInfo, err := src.Stat()
+	if err != nil || !srcInfo.Mode().IsRegular() || srcInfo.Size() == 0 {
+		return 0, false
+	}
+	dstInfo, err := f.Stat()
+	if err != nil || !dstInfo.Mode().IsRegular() || dstInfo.Size() != 0 {
+		return 0, false
+	}
+	if !reflink(f.File, src.File) {
+		return 0, false
+	}
+
+	// leave the offsets where a copy would
+	size := srcInfo.Size()
+	if _, err := src.Seek(size, io.SeekStart); err != nil {
+		return 0, false
+	}
+	if _, err := f.Seek(size, io.SeekStart); err != nil {
+		return 0, false
+	}
+	return size, true
+}
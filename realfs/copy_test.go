@@ -0,0 +1,83 @@
+package realfs_test
+
+import (
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/daaku/go.fs"
+	"github.com/daaku/go.fs/realfs"
+)
+
+func TestCopy(t *testing.T) {
+	t.Parallel()
+	dir, err := ioutil.TempDir("", "realfs_test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	src := filepath.Join(dir, "src")
+	if err := ioutil.WriteFile(src, []byte("0123456789"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	cases := []struct {
+		Name     string
+		System   fs.System
+		Copy     func(dst io.Writer, src io.Reader) (int64, error)
+		Offset   int64
+		Expected string
+	}{
+		{"whole", realfs.New(), io.Copy, 0, "0123456789"},
+		{"offset", realfs.New(), io.Copy, 4, "456789"},
+		{"limited", realfs.New(), func(dst io.Writer, src io.Reader) (int64, error) {
+			return io.CopyN(dst, src, 3)
+		}, 2, "234"},
+		{"mapped", realfs.NewWithConfig(realfs.Config{Mmap: true}), io.Copy, 8, "89"},
+	}
+	for _, c := range cases {
+		r, err := c.System.Open(src)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := r.Seek(c.Offset, io.SeekStart); err != nil {
+			t.Fatal(err)
+		}
+		w, err := c.System.Create(filepath.Join(dir, c.Name))
+		if err != nil {
+			t.Fatal(err)
+		}
+		n, err := c.Copy(w, r)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if n != int64(len(c.Expected)) {
+			t.Fatalf("%s: was expecting %d bytes, got %d", c.Name, len(c.Expected), n)
+		}
+
+		// the offsets are left after the copied data
+		if _, err := w.Write([]byte("x")); err != nil {
+			t.Fatal(err)
+		}
+		rest, err := ioutil.ReadAll(r)
+		if err != nil {
+			t.Fatal(err)
+		}
+		expectedRest := "0123456789"[c.Offset+n:]
+		if string(rest) != expectedRest {
+			t.Fatalf("%s: was expecting %q to remain, got %q", c.Name, expectedRest, rest)
+		}
+		r.Close()
+		w.Close()
+
+		b, err := ioutil.ReadFile(filepath.Join(dir, c.Name))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(b) != c.Expected+"x" {
+			t.Fatalf("%s: was expecting %s, got %s", c.Name, c.Expected+"x", b)
+		}
+	}
+}
@@ -0,0 +1,12 @@
+package realfs
+
+// NewRootedWithoutOpenat2 is like NewRooted, but always uses the os.Root so
+// that path is also tested on Linux.
+func NewRootedWithoutOpenat2(dir string) (*Rooted, error) {
+	r, err := NewRooted(dir)
+	if err != nil {
+		return nil, err
+	}
+	r.noOpenat2 = true
+	return r, nil
+}
@@ -0,0 +1,14 @@
+//go:build !unix && !js && !wasip1
+
+package realfs
+
+import (
+	"os"
+
+	"github.com/daaku/go.fs"
+)
+
+// There is no portable way to get the Ident here.
+func infoIdent(name string, fi os.FileInfo) (fs.Ident, error) {
+	return fs.Ident{}, &fs.PathError{Op: "ident", Path: name, Err: fs.ErrNotSupported}
+}
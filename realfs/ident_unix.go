@@ -0,0 +1,25 @@
+//go:build unix || js || wasip1
+
+package realfs
+
+import (
+	"os"
+	"syscall"
+
+	"github.com/daaku/go.fs"
+)
+
+// Extracts the Ident from the *syscall.Stat_t for the FileInfo.
+func infoIdent(name string, fi os.FileInfo) (fs.Ident, error) {
+	st, ok := fi.Sys().(*syscall.Stat_t)
+	if !ok {
+		return fs.Ident{}, &fs.PathError{Op: "ident", Path: name, Err: fs.ErrNotSupported}
+	}
+	return fs.Ident{
+		UID:    int(st.Uid),
+		GID:    int(st.Gid),
+		Inode:  uint64(st.Ino),
+		Device: uint64(st.Dev),
+		Nlink:  uint64(st.Nlink),
+	}, nil
+}
@@ -0,0 +1,59 @@
+//go:build unix && !aix && !solaris
+
+package realfs
+
+import (
+	"syscall"
+
+	"github.com/daaku/go.fs"
+)
+
+// Lock uses flock, which is owned by the open file rather than the process, so
+// Files opened separately contend for it even within a process, and it works
+// with Files opened read-only.
+func (f file) Lock() error {
+	_, err := f.flock("lock", syscall.LOCK_EX)
+	return err
+}
+
+func (f file) RLock() error {
+	_, err := f.flock("lock", syscall.LOCK_SH)
+	return err
+}
+
+func (f file) TryLock() (bool, error) {
+	return f.flock("lock", syscall.LOCK_EX|syscall.LOCK_NB)
+}
+
+func (f file) Unlock() error {
+	_, err := f.flock("unlock", syscall.LOCK_UN)
+	return err
+}
+
+// Applies the flock operation, returning false if a non-blocking request
+// conflicts with a lock held by another File.
+func (f file) flock(op string, how int) (bool, error) {
+	conn, err := f.SyscallConn()
+	if err != nil {
+		return false, &fs.PathError{Op: op, Path: f.Name(), Err: err}
+	}
+	var lockErr error
+	err = conn.Control(func(fd uintptr) {
+		for {
+			lockErr = syscall.Flock(int(fd), how)
+			if lockErr != syscall.EINTR {
+				return
+			}
+		}
+	})
+	if err == nil {
+		err = lockErr
+	}
+	if err == syscall.EWOULDBLOCK {
+		return false, nil
+	}
+	if err != nil {
+		return false, &fs.PathError{Op: op, Path: f.Name(), Err: err}
+	}
+	return true, nil
+}
@@ -0,0 +1,24 @@
+//go:build !unix || aix || solaris
+
+package realfs
+
+import (
+	"github.com/daaku/go.fs"
+)
+
+// Locks use flock, which only some Unix systems provide.
+func (f file) Lock() error {
+	return &fs.PathError{Op: "lock", Path: f.Name(), Err: fs.ErrNotSupported}
+}
+
+func (f file) RLock() error {
+	return &fs.PathError{Op: "lock", Path: f.Name(), Err: fs.ErrNotSupported}
+}
+
+func (f file) TryLock() (bool, error) {
+	return false, &fs.PathError{Op: "lock", Path: f.Name(), Err: fs.ErrNotSupported}
+}
+
+func (f file) Unlock() error {
+	return &fs.PathError{Op: "unlock", Path: f.Name(), Err: fs.ErrNotSupported}
+}
@@ -0,0 +1,20 @@
+package realfs
+
+import (
+	"github.com/daaku/go.fs"
+)
+
+// A MappedFile is a File opened for reading using mmap, which allows reading
+// without a system call. Only non empty regular files are mapped, other files
+// are opened as usual and do not implement MappedFile. Files are only mapped
+// on Unix.
+//
+// The file must not be truncated while it is mapped, since accessing the
+// missing pages would crash the process.
+type MappedFile interface {
+	fs.File
+
+	// Bytes returns the contents of the file without copying. The slice must
+	// not be modified, and is only valid until the File is closed.
+	Bytes() []byte
+}
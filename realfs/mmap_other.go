@@ -0,0 +1,14 @@
+//go:build !unix
+
+package realfs
+
+import (
+	"os"
+
+	"github.com/daaku/go.fs"
+)
+
+// Files are opened as usual where mmap isn't available.
+func mmap(f *os.File) (fs.File, error) {
+	return file{f}, nil
+}
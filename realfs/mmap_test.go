@@ -0,0 +1,54 @@
+package realfs_test
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/daaku/go.fs"
+	"github.com/daaku/go.fs/fstest"
+	"github.com/daaku/go.fs/realfs"
+)
+
+func TestMmapFallback(t *testing.T) {
+	t.Parallel()
+	dir, err := ioutil.TempDir("", "realfs_test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	name := filepath.Join(dir, "empty")
+	if err := ioutil.WriteFile(name, nil, 0644); err != nil {
+		t.Fatal(err)
+	}
+	s := realfs.NewWithConfig(realfs.Config{Mmap: true})
+	for _, name := range []string{name, dir} {
+		f, err := s.Open(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, ok := f.(realfs.MappedFile); ok {
+			t.Fatalf("%s: was not expecting a MappedFile", name)
+		}
+		f.Close()
+	}
+}
+
+func TestMmapConformance(t *testing.T) {
+	t.Parallel()
+	fstest.TestSystem(t, fstest.Config{
+		New: func(t *testing.T, files map[string]string) (fs.System, string) {
+			dir, err := ioutil.TempDir("", "realfs_test")
+			if err != nil {
+				t.Fatal(err)
+			}
+			t.Cleanup(func() { os.RemoveAll(dir) })
+			s := realfs.NewWithConfig(realfs.Config{Mmap: true})
+			if err := fstest.Populate(s, dir, files); err != nil {
+				t.Fatal(err)
+			}
+			return s, dir
+		},
+	})
+}
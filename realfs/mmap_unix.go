@@ -0,0 +1,142 @@
+//go:build unix
+
+package realfs
+
+import (
+	"io"
+	"os"
+	"sync"
+	"syscall"
+
+	"github.com/daaku/go.fs"
+)
+
+// Maps the opened file if possible, taking ownership of it.
+func mmap(f *os.File) (fs.File, error) {
+	fi, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return nil, err
+	}
+	size := fi.Size()
+	if !fi.Mode().IsRegular() || size <= 0 || int64(int(size)) != size {
+		return file{f}, nil
+	}
+	data, err := syscall.Mmap(int(f.Fd()), 0, int(size), syscall.PROT_READ, syscall.MAP_SHARED)
+	if err != nil {
+		// some file systems do not support mmap
+		return file{f}, nil
+	}
+	return &mapped{file: file{f}, data: data}, nil
+}
+
+// A mapped file has its own offset, since reading the mapping does not move
+// the one of the file descriptor. Read, Seek and WriteTo all use it, and the
+// methods that write fail without moving either, since the file is only open
+// for reading.
+type mapped struct {
+	file
+	mu   sync.RWMutex // held for reading while the data is in use
+	data []byte       // nil once closed
+	off  int64        // offset for the next Read
+}
+
+func (m *mapped) Bytes() []byte {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return m.data
+}
+
+// Close waits for any reads in progress before unmapping the data.
+func (m *mapped) Close() error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.data == nil {
+		return m.pathError("close", fs.ErrClosed)
+	}
+	err := syscall.Munmap(m.data)
+	m.data = nil
+	if errC := m.file.Close(); err == nil {
+		err = errC
+	}
+	return err
+}
+
+func (m *mapped) Read(b []byte) (n int, err error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.data == nil {
+		return 0, m.pathError("read", fs.ErrClosed)
+	}
+	if m.off >= int64(len(m.data)) {
+		if len(b) == 0 {
+			return 0, nil
+		}
+		return 0, io.EOF
+	}
+	n = copy(b, m.data[m.off:])
+	m.off += int64(n)
+	return n, nil
+}
+
+// ReadAt does not affect the offset used by Read.
+func (m *mapped) ReadAt(b []byte, off int64) (n int, err error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	if m.data == nil {
+		return 0, m.pathError("read", fs.ErrClosed)
+	}
+	if off < 0 {
+		return 0, m.pathError("read", fs.ErrInvalid)
+	}
+	if off >= int64(len(m.data)) {
+		return 0, io.EOF
+	}
+	n = copy(b, m.data[off:])
+	if n < len(b) {
+		err = io.EOF
+	}
+	return n, err
+}
+
+func (m *mapped) Seek(offset int64, whence int) (ret int64, err error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.data == nil {
+		return 0, m.pathError("seek", fs.ErrClosed)
+	}
+	switch whence {
+	case io.SeekStart:
+		ret = offset
+	case io.SeekCurrent:
+		ret = m.off + offset
+	case io.SeekEnd:
+		ret = int64(len(m.data)) + offset
+	default:
+		return m.off, m.pathError("seek", fs.ErrInvalid)
+	}
+	if ret < 0 {
+		return m.off, m.pathError("seek", fs.ErrInvalid)
+	}
+	m.off = ret
+	return ret, nil
+}
+
+// Writes the remaining mapped data directly.
+func (m *mapped) WriteTo(w io.Writer) (int64, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.data == nil {
+		return 0, m.pathError("read", fs.ErrClosed)
+	}
+	if m.off >= int64(len(m.data)) {
+		return 0, nil
+	}
+	n, err := w.Write(m.data[m.off:])
+	m.off += int64(n)
+	return int64(n), err
+}
+
+func (m *mapped) pathError(op string, err error) error {
+	return &fs.PathError{Op: op, Path: m.Name(), Err: err}
+}
@@ -0,0 +1,143 @@
+//go:build unix
+
+package realfs_test
+
+import (
+	"errors"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+
+	"github.com/daaku/go.fs"
+	"github.com/daaku/go.fs/realfs"
+)
+
+// Opens a mapped file containing 0123456789.
+func openMapped(t *testing.T) fs.File {
+	t.Helper()
+	dir, err := ioutil.TempDir("", "realfs_test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.RemoveAll(dir) })
+	name := filepath.Join(dir, "foo")
+	if err := ioutil.WriteFile(name, []byte("0123456789"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	f, err := realfs.NewWithConfig(realfs.Config{Mmap: true}).Open(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := f.(realfs.MappedFile); !ok {
+		t.Fatalf("was expecting a MappedFile, got %T", f)
+	}
+	return f
+}
+
+func TestMmap(t *testing.T) {
+	t.Parallel()
+	dir, err := ioutil.TempDir("", "realfs_test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	name := filepath.Join(dir, "foo")
+	if err := ioutil.WriteFile(name, []byte("0123456789"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	s := realfs.NewWithConfig(realfs.Config{Mmap: true})
+	f, err := s.Open(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	m, ok := f.(realfs.MappedFile)
+	if !ok {
+		t.Fatalf("was expecting a MappedFile, got %T", f)
+	}
+	if string(m.Bytes()) != "0123456789" {
+		t.Fatalf("was expecting 0123456789, got %s", m.Bytes())
+	}
+	b := make([]byte, 4)
+	n, err := f.ReadAt(b, 8)
+	if n != 2 || err != io.EOF || string(b[:n]) != "89" {
+		t.Fatalf("was expecting 2 bytes and io.EOF, got %d %v %q", n, err, b[:n])
+	}
+	if _, err := f.Seek(-3, io.SeekEnd); err != nil {
+		t.Fatal(err)
+	}
+	rest, err := ioutil.ReadAll(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(rest) != "789" {
+		t.Fatalf("was expecting 789, got %s", rest)
+	}
+	if err := f.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := f.ReadAt(b, 0); !errors.Is(err, fs.ErrClosed) {
+		t.Fatalf("was expecting ErrClosed, got %v", err)
+	}
+
+	// files opened for writing are not mapped
+	f, err = s.OpenFile(name, os.O_RDWR, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	if _, ok := f.(realfs.MappedFile); ok {
+		t.Fatal("was not expecting a MappedFile")
+	}
+}
+
+func TestMmapCloseWhileReading(t *testing.T) {
+	t.Parallel()
+	f := openMapped(t)
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			b := make([]byte, 4)
+			for {
+				if _, err := f.ReadAt(b, 2); errors.Is(err, fs.ErrClosed) {
+					return
+				}
+				if string(b) != "2345" {
+					t.Errorf("was expecting 2345, got %q", b)
+					return
+				}
+			}
+		}()
+	}
+	if err := f.Close(); err != nil {
+		t.Fatal(err)
+	}
+	wg.Wait()
+}
+
+func TestMmapOffset(t *testing.T) {
+	t.Parallel()
+	f := openMapped(t)
+	defer f.Close()
+	b := make([]byte, 4)
+	if _, err := f.Read(b); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := f.Write([]byte("x")); err == nil {
+		t.Fatal("was expecting an error writing to a read only file")
+	}
+	if off, err := f.Seek(0, io.SeekCurrent); err != nil || off != 4 {
+		t.Fatalf("was expecting offset 4, got %d %v", off, err)
+	}
+	rest, err := ioutil.ReadAll(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(rest) != "456789" {
+		t.Fatalf("was expecting 456789, got %s", rest)
+	}
+}
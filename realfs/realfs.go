@@ -2,14 +2,22 @@
 package realfs
 
 import (
+	"io/ioutil"
 	"os"
-	"syscall"
+	"time"
 
 	"github.com/daaku/go.fs"
 	"github.com/daaku/go.fs/fsutil"
 )
 
-type system struct{}
+// Defines a Config to customize how files are accessed.
+type Config struct {
+	Mmap bool // open files for reading using mmap, see MappedFile
+}
+
+type system struct {
+	Config Config
+}
 
 var singleton = system{}
 
@@ -18,11 +26,19 @@ func New() fs.System {
 	return singleton
 }
 
+// Provides access to the real file system customized by the Config.
+func NewWithConfig(c Config) fs.System {
+	return system{Config: c}
+}
+
 func (s system) Open(name string) (fs.File, error) {
 	f, err := os.Open(name)
 	if err != nil {
 		return nil, err
 	}
+	if s.Config.Mmap {
+		return mmap(f)
+	}
 	return file{f}, nil
 }
 
@@ -30,22 +46,121 @@ func (s system) IsNotExist(err error) bool {
 	return fsutil.IsNotExist(err)
 }
 
+func (s system) Stat(name string) (os.FileInfo, error) {
+	return os.Stat(name)
+}
+
+func (s system) Lstat(name string) (os.FileInfo, error) {
+	return os.Lstat(name)
+}
+
+func (s system) ReadDir(name string) ([]os.FileInfo, error) {
+	return ioutil.ReadDir(name)
+}
+
+func (s system) ReadFile(name string) ([]byte, error) {
+	return ioutil.ReadFile(name)
+}
+
+func (s system) WriteFile(name string, data []byte, perm os.FileMode) error {
+	return ioutil.WriteFile(name, data, perm)
+}
+
+func (s system) Symlink(oldname, newname string) error {
+	return os.Symlink(oldname, newname)
+}
+
+func (s system) Readlink(name string) (string, error) {
+	return os.Readlink(name)
+}
+
+func (s system) Chmod(name string, mode os.FileMode) error {
+	return os.Chmod(name, mode)
+}
+
+func (s system) Chown(name string, uid, gid int) error {
+	return os.Chown(name, uid, gid)
+}
+
+func (s system) Chtimes(name string, atime time.Time, mtime time.Time) error {
+	return os.Chtimes(name, atime, mtime)
+}
+
+func (s system) Ident(name string) (fs.Ident, error) {
+	fi, err := os.Stat(name)
+	if err != nil {
+		return fs.Ident{}, err
+	}
+	return infoIdent(name, fi)
+}
+
+func (s system) Truncate(name string, size int64) error {
+	return os.Truncate(name, size)
+}
+
+func (s system) Create(name string) (fs.File, error) {
+	f, err := os.Create(name)
+	if err != nil {
+		return nil, err
+	}
+	return file{f}, nil
+}
+
+func (s system) OpenFile(name string, flag int, perm os.FileMode) (fs.File, error) {
+	f, err := os.OpenFile(name, flag, perm)
+	if err != nil {
+		return nil, err
+	}
+	if s.Config.Mmap && !fsutil.WantsWrite(flag) {
+		return mmap(f)
+	}
+	return file{f}, nil
+}
+
+func (s system) Mkdir(name string, perm os.FileMode) error {
+	return os.Mkdir(name, perm)
+}
+
+func (s system) MkdirAll(path string, perm os.FileMode) error {
+	return os.MkdirAll(path, perm)
+}
+
+func (s system) Remove(name string) error {
+	return os.Remove(name)
+}
+
+func (s system) RemoveAll(path string) error {
+	return os.RemoveAll(path)
+}
+
+func (s system) Rename(oldname, newname string) error {
+	return os.Rename(oldname, newname)
+}
+
 type file struct {
 	*os.File
 }
 
-func (f file) OwnerGID() (int, error) {
+func (f file) Ident() (fs.Ident, error) {
 	fi, err := f.Stat()
 	if err != nil {
-		return 0, err
+		return fs.Ident{}, err
 	}
-	return int(fi.Sys().(*syscall.Stat_t).Uid), nil
+	return infoIdent(f.Name(), fi)
 }
 
-func (f file) OwnerUID() (int, error) {
-	fi, err := f.Stat()
+// Runs fn with the file descriptor, returning its error as a PathError.
+func (f file) control(op string, fn func(fd uintptr) error) error {
+	conn, err := f.SyscallConn()
 	if err != nil {
-		return 0, err
+		return &fs.PathError{Op: op, Path: f.Name(), Err: err}
+	}
+	var fnErr error
+	if err := conn.Control(func(fd uintptr) { fnErr = fn(fd) }); err != nil {
+		return &fs.PathError{Op: op, Path: f.Name(), Err: err}
+	}
+	if fnErr != nil {
+		return &fs.PathError{Op: op, Path: f.Name(), Err: fnErr}
 	}
-	return int(fi.Sys().(*syscall.Stat_t).Gid), nil
+	return nil
 }
@@ -1,10 +1,19 @@
 package realfs_test
 
 import (
+	"errors"
 	"io/ioutil"
 	"os"
+	"path/filepath"
+	"reflect"
+	"runtime"
+	"syscall"
 	"testing"
+	"time"
 
+	"github.com/daaku/go.fs"
+	"github.com/daaku/go.fs/fstest"
+	"github.com/daaku/go.fs/fsutil"
 	"github.com/daaku/go.fs/realfs"
 )
 
@@ -29,37 +38,63 @@ func TestOpenNotExist(t *testing.T) {
 	}
 }
 
-func TestGetUGID(t *testing.T) {
+func TestIdent(t *testing.T) {
 	t.Parallel()
-	tf, err := ioutil.TempFile("", "realfs_test")
+	dir, err := ioutil.TempDir("", "realfs_test")
 	if err != nil {
 		t.Fatal(err)
 	}
-	name := tf.Name()
-	defer os.Remove(name)
+	defer os.RemoveAll(dir)
+	name := filepath.Join(dir, "foo")
+	if err := ioutil.WriteFile(name, nil, 0644); err != nil {
+		t.Fatal(err)
+	}
 	s := realfs.New()
 	f, err := s.Open(name)
 	if err != nil {
 		t.Fatal(err)
 	}
-	expectedUID, expectedGID := os.Getuid(), os.Getgid()
-	actualGID, err := f.OwnerGID()
+	defer f.Close()
+	ident, err := f.Ident()
 	if err != nil {
 		t.Fatal(err)
 	}
-	if actualGID != expectedGID {
-		t.Fatal("did not find expected gid")
+	if ident.UID != os.Getuid() || ident.GID != os.Getgid() {
+		t.Fatalf("did not find expected uid and gid, got %+v", ident)
+	}
+	if ident.Inode == 0 || ident.Nlink != 1 {
+		t.Fatalf("did not find expected inode and nlink, got %+v", ident)
+	}
+
+	// a hard link is the same file
+	link := filepath.Join(dir, "link")
+	if err := os.Link(name, link); err != nil {
+		t.Fatal(err)
 	}
-	actualUID, err := f.OwnerUID()
+	linked, err := fsutil.Ident(s, link)
 	if err != nil {
 		t.Fatal(err)
 	}
-	if actualUID != expectedUID {
-		t.Fatal("did not find expected uid")
+	if linked.Inode != ident.Inode || linked.Device != ident.Device || linked.Nlink != 2 {
+		t.Fatalf("was expecting the same file with 2 links, got %+v and %+v", ident, linked)
+	}
+
+	// distinct ids catch the uid and gid being swapped
+	if os.Getuid() == 0 {
+		if err := fsutil.Chown(s, name, 1, 2); err != nil {
+			t.Fatal(err)
+		}
+		ident, err := fsutil.Ident(s, name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if ident.UID != 1 || ident.GID != 2 {
+			t.Fatalf("was expecting uid 1 and gid 2, got %+v", ident)
+		}
 	}
 }
 
-func TestGetUGIDError(t *testing.T) {
+func TestIdentError(t *testing.T) {
 	t.Parallel()
 	tf, err := ioutil.TempFile("", "realfs_test")
 	if err != nil {
@@ -74,10 +109,469 @@ func TestGetUGIDError(t *testing.T) {
 	tf.Close()
 	f.Close()
 	os.Remove(name)
-	if _, err := f.OwnerGID(); err == nil {
+	if _, err := f.Ident(); err == nil {
 		t.Fatal("was expecting error")
 	}
-	if _, err := f.OwnerUID(); err == nil {
+	if _, err := fsutil.Ident(s, name); !s.IsNotExist(err) {
+		t.Fatalf("was expecting is not exist error, got %v", err)
+	}
+}
+
+func TestWriteOperations(t *testing.T) {
+	t.Parallel()
+	dir, err := ioutil.TempDir("", "realfs_test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	s := realfs.New()
+	if err := s.MkdirAll(filepath.Join(dir, "a", "b"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	name := filepath.Join(dir, "a", "b", "foo")
+	f, err := s.Create(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := f.WriteString("bar"); err != nil {
+		t.Fatal(err)
+	}
+	if err := f.Close(); err != nil {
+		t.Fatal(err)
+	}
+	renamed := filepath.Join(dir, "a", "foo")
+	if err := s.Rename(name, renamed); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.Open(name); !s.IsNotExist(err) {
+		t.Fatalf("was expecting is not exist error, got %v", err)
+	}
+	f, err = s.OpenFile(renamed, os.O_RDONLY, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := ioutil.ReadAll(f)
+	f.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(b) != "bar" {
+		t.Fatalf("was expecting bar, got %s", b)
+	}
+	if err := s.Remove(renamed); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.RemoveAll(filepath.Join(dir, "a")); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.Open(filepath.Join(dir, "a")); !s.IsNotExist(err) {
+		t.Fatalf("was expecting is not exist error, got %v", err)
+	}
+}
+
+func TestStatAndLstat(t *testing.T) {
+	t.Parallel()
+	dir, err := ioutil.TempDir("", "realfs_test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	name := filepath.Join(dir, "foo")
+	if err := ioutil.WriteFile(name, []byte("bar"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	link := filepath.Join(dir, "link")
+	if err := os.Symlink(name, link); err != nil {
+		t.Fatal(err)
+	}
+	s := realfs.New().(fs.LstatSystem)
+	fi, err := fsutil.Stat(s, link)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fi.Size() != 3 || !fi.Mode().IsRegular() {
+		t.Fatalf("did not find expected file info: %v", fi)
+	}
+	fi, err = s.Lstat(link)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fi.Mode()&os.ModeSymlink == 0 {
+		t.Fatalf("was expecting symlink, got %v", fi.Mode())
+	}
+	if _, err := fsutil.Stat(s, filepath.Join(dir, "missing")); !s.IsNotExist(err) {
+		t.Fatalf("was expecting is not exist error, got %v", err)
+	}
+}
+
+func TestCapabilities(t *testing.T) {
+	t.Parallel()
+	dir, err := ioutil.TempDir("", "realfs_test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	s := realfs.New()
+	name := filepath.Join(dir, "foo")
+	if err := ioutil.WriteFile(name, []byte("bar"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	link := filepath.Join(dir, "link")
+	if err := fsutil.Symlink(s, name, link); err != nil {
+		t.Fatal(err)
+	}
+	target, err := fsutil.Readlink(s, link)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if target != name {
+		t.Fatalf("was expecting %s, got %s", name, target)
+	}
+	if err := fsutil.Truncate(s, name, 1); err != nil {
+		t.Fatal(err)
+	}
+	b, err := fsutil.ReadFile(s, link)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(b) != "b" {
+		t.Fatalf("was expecting b, got %s", b)
+	}
+	infos, err := fsutil.ReadDir(s, dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(infos) != 2 || infos[0].Name() != "foo" || infos[1].Name() != "link" {
+		t.Fatalf("did not find expected infos, got %v", infos)
+	}
+}
+
+func TestConformance(t *testing.T) {
+	t.Parallel()
+	fstest.TestSystem(t, fstest.Config{
+		New: func(t *testing.T, files map[string]string) (fs.System, string) {
+			dir, err := ioutil.TempDir("", "realfs_test")
+			if err != nil {
+				t.Fatal(err)
+			}
+			t.Cleanup(func() { os.RemoveAll(dir) })
+			s := realfs.New()
+			if err := fstest.Populate(s, dir, files); err != nil {
+				t.Fatal(err)
+			}
+			return s, dir
+		},
+	})
+}
+
+// Waits for an Event with the given name and op, skipping others.
+func waitEvent(t *testing.T, w fs.Watcher, name string, op fs.Op) {
+	t.Helper()
+	timeout := time.After(5 * time.Second)
+	for {
+		select {
+		case ev := <-w.Events():
+			if ev.Name == name && ev.Op&op != 0 {
+				return
+			}
+		case err := <-w.Errors():
+			t.Fatal(err)
+		case <-timeout:
+			t.Fatalf("was expecting %v %s", op, name)
+		}
+	}
+}
+
+func TestWatch(t *testing.T) {
+	if runtime.GOOS != "linux" {
+		t.Skip("watch is only supported on linux")
+	}
+	t.Parallel()
+	dir, err := ioutil.TempDir("", "realfs_test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	s := realfs.New()
+	w, err := fsutil.Watch(s, dir, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer w.Close()
+
+	name := filepath.Join(dir, "foo")
+	if err := fsutil.WriteFile(s, name, []byte("bar"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	waitEvent(t, w, name, fs.OpCreate)
+	waitEvent(t, w, name, fs.OpWrite)
+
+	// directories created after starting are also watched
+	nested := filepath.Join(dir, "a", "b")
+	if err := s.MkdirAll(nested, 0755); err != nil {
+		t.Fatal(err)
+	}
+	waitEvent(t, w, filepath.Join(dir, "a"), fs.OpCreate)
+	// give the watcher a chance to add the nested watches
+	time.Sleep(50 * time.Millisecond)
+	name = filepath.Join(nested, "baz")
+	if err := fsutil.WriteFile(s, name, nil, 0644); err != nil {
+		t.Fatal(err)
+	}
+	waitEvent(t, w, name, fs.OpCreate)
+	if err := s.Remove(name); err != nil {
+		t.Fatal(err)
+	}
+	waitEvent(t, w, name, fs.OpRemove)
+
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	for range w.Events() {
+	}
+}
+
+func TestWatchNotExist(t *testing.T) {
+	t.Parallel()
+	_, err := fsutil.Watch(realfs.New(), "/foo/bar/baz/boom", false)
+	if err == nil {
 		t.Fatal("was expecting error")
 	}
 }
+
+func TestLock(t *testing.T) {
+	t.Parallel()
+	tf, err := ioutil.TempFile("", "realfs_test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	tf.Close()
+	name := tf.Name()
+	defer os.Remove(name)
+	s := realfs.New()
+	f1, err := s.Open(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f1.Close()
+	f2, err := s.Open(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f2.Close()
+
+	tryLock := func(f fs.File, expected bool) {
+		t.Helper()
+		locked, err := fsutil.TryLock(f)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if locked != expected {
+			t.Fatalf("was expecting TryLock to return %v", expected)
+		}
+	}
+
+	if err := fsutil.Lock(f1); err != nil {
+		t.Fatal(err)
+	}
+	tryLock(f2, false)
+
+	// converting to a shared lock allows other readers, but not writers
+	if err := fsutil.RLock(f1); err != nil {
+		t.Fatal(err)
+	}
+	if err := fsutil.RLock(f2); err != nil {
+		t.Fatal(err)
+	}
+	tryLock(f2, false)
+
+	// a blocked Lock is acquired once the other File unlocks
+	acquired := make(chan error)
+	go func() { acquired <- fsutil.Lock(f2) }()
+	select {
+	case err := <-acquired:
+		t.Fatalf("was expecting Lock to block, got %v", err)
+	case <-time.After(50 * time.Millisecond):
+	}
+	if err := fsutil.Unlock(f1); err != nil {
+		t.Fatal(err)
+	}
+	if err := <-acquired; err != nil {
+		t.Fatal(err)
+	}
+	tryLock(f1, false)
+
+	// closing releases the lock
+	if err := f2.Close(); err != nil {
+		t.Fatal(err)
+	}
+	tryLock(f1, true)
+}
+
+func TestXattr(t *testing.T) {
+	t.Parallel()
+	tf, err := ioutil.TempFile("", "realfs_test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	tf.Close()
+	name := tf.Name()
+	defer os.Remove(name)
+	s := realfs.New()
+	err = fsutil.Setxattr(s, name, "user.realfs_test", []byte("bar"))
+	if errors.Is(err, fs.ErrNotSupported) || errors.Is(err, syscall.ENOTSUP) {
+		t.Skip(err)
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := fsutil.Getxattr(s, name, "user.realfs_test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(b) != "bar" {
+		t.Fatalf("was expecting bar, got %s", b)
+	}
+	names, err := fsutil.Listxattr(s, name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(names, []string{"user.realfs_test"}) {
+		t.Fatalf("did not find expected names, got %v", names)
+	}
+	if err := fsutil.Removexattr(s, name, "user.realfs_test"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := fsutil.Getxattr(s, name, "user.realfs_test"); !errors.Is(err, fs.ErrNoAttr) {
+		t.Fatalf("was expecting ErrNoAttr, got %v", err)
+	}
+}
+
+func TestFileXattr(t *testing.T) {
+	t.Parallel()
+	tf, err := ioutil.TempFile("", "realfs_test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	tf.Close()
+	name := tf.Name()
+	defer os.Remove(name)
+	f, err := realfs.New().Open(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	xf, ok := f.(fs.XattrFile)
+	if !ok {
+		t.Fatal("was expecting a XattrFile")
+	}
+	err = xf.Setxattr("user.realfs_test", []byte("bar"))
+	if errors.Is(err, fs.ErrNotSupported) || errors.Is(err, syscall.ENOTSUP) {
+		t.Skip(err)
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// the open file keeps working after it is renamed
+	renamed := name + ".renamed"
+	if err := os.Rename(name, renamed); err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove(renamed)
+	b, err := xf.Getxattr("user.realfs_test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(b) != "bar" {
+		t.Fatalf("was expecting bar, got %s", b)
+	}
+	names, err := xf.Listxattr()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(names, []string{"user.realfs_test"}) {
+		t.Fatalf("did not find expected names, got %v", names)
+	}
+	if err := xf.Removexattr("user.realfs_test"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := xf.Getxattr("user.realfs_test"); !errors.Is(err, fs.ErrNoAttr) {
+		t.Fatalf("was expecting ErrNoAttr, got %v", err)
+	}
+	if names, err := xf.Listxattr(); err != nil || len(names) != 0 {
+		t.Fatalf("was expecting no names, got %v and %v", names, err)
+	}
+}
+
+func TestTimes(t *testing.T) {
+	t.Parallel()
+	tf, err := ioutil.TempFile("", "realfs_test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	tf.Close()
+	name := tf.Name()
+	defer os.Remove(name)
+	s := realfs.New()
+	atime := time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC)
+	mtime := atime.Add(time.Hour)
+	if err := fsutil.Chtimes(s, name, atime, mtime); err != nil {
+		t.Fatal(err)
+	}
+	times, err := fsutil.Times(s, name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !times.Mtime.Equal(mtime) {
+		t.Fatalf("was expecting mtime %v, got %v", mtime, times.Mtime)
+	}
+	if runtime.GOOS == "linux" {
+		if !times.Atime.Equal(atime) || times.Ctime.Before(mtime) {
+			t.Fatalf("did not find expected times, got %v", times)
+		}
+	}
+	if _, err := fsutil.Times(s, filepath.Join(name, "missing")); err == nil {
+		t.Fatal("was expecting error")
+	}
+}
+
+func TestFileTimes(t *testing.T) {
+	t.Parallel()
+	tf, err := ioutil.TempFile("", "realfs_test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	tf.Close()
+	name := tf.Name()
+	defer os.Remove(name)
+	atime := time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC)
+	mtime := atime.Add(time.Hour)
+	if err := os.Chtimes(name, atime, mtime); err != nil {
+		t.Fatal(err)
+	}
+	f, err := realfs.New().Open(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	// the open file is described even after it is renamed
+	renamed := name + ".renamed"
+	if err := os.Rename(name, renamed); err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove(renamed)
+	times, err := fsutil.FileTimes(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !times.Mtime.Equal(mtime) {
+		t.Fatalf("was expecting mtime %v, got %v", mtime, times.Mtime)
+	}
+	if runtime.GOOS == "linux" {
+		if !times.Atime.Equal(atime) || times.Ctime.Before(mtime) {
+			t.Fatalf("did not find expected times, got %v", times)
+		}
+	}
+}
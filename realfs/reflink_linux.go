@@ -0,0 +1,42 @@
+//go:build linux
+
+package realfs
+
+import (
+	"os"
+	"runtime"
+	"syscall"
+)
+
+// Returns the FICLONE ioctl, which the syscall package does not provide. It is
+// _IOW(0x94, 9, int), which is encoded differently on some architectures.
+func ficlone() uintptr {
+	switch runtime.GOARCH {
+	case "mips", "mipsle", "mips64", "mips64le", "ppc64", "ppc64le":
+		return 0x80049409
+	}
+	return 0x40049409
+}
+
+// Makes dst share the data of src, returning false if the file system does
+// not support it.
+func reflink(dst, src *os.File) bool {
+	dstConn, err := dst.SyscallConn()
+	if err != nil {
+		return false
+	}
+	srcConn, err := src.SyscallConn()
+	if err != nil {
+		return false
+	}
+	var errno syscall.Errno
+	err = dstConn.Control(func(dstFd uintptr) {
+		err := srcConn.Control(func(srcFd uintptr) {
+			_, _, errno = syscall.Syscall(syscall.SYS_IOCTL, dstFd, ficlone(), srcFd)
+		})
+		if err != nil {
+			errno = syscall.EBADF
+		}
+	})
+	return err == nil && errno == 0
+}
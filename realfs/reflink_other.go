@@ -0,0 +1,12 @@
+//go:build !linux
+
+package realfs
+
+import (
+	"os"
+)
+
+// Reflinks are only supported on Linux.
+func reflink(dst, src *os.File) bool {
+	return false
+}
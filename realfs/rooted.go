@@ -0,0 +1,208 @@
+package realfs
+
+import (
+	"io/ioutil"
+	"os"
+	"time"
+
+	"github.com/daaku/go.fs"
+	"github.com/daaku/go.fs/fsutil"
+)
+
+// Rooted is a System confined to a directory, see NewRooted.
+type Rooted struct {
+	root      *os.Root
+	dir       *os.File // the root directory, used for openat2
+	noOpenat2 bool     // set when openat2 can't be used, see probe
+}
+
+// NewRooted provides access to the real file system beneath dir. Names are
+// relative to dir, with leading slashes and ".." elements that would go above
+// it removed. Symbolic links are followed only if they resolve beneath dir,
+// so neither names nor links can be used to access files outside of it.
+//
+// On Linux files are opened and described using openat2 with
+// RESOLVE_BENEATH, which has the kernel enforce this. Otherwise, and for the
+// remaining operations, each path element is resolved in userspace using the
+// *at family of system calls, see os.Root. This is also used on Linux if
+// openat2 is missing, or blocked as some container runtimes do.
+//
+// The Rooted System must be closed to release the directory. It requires Go
+// 1.25 or later, for the os.Root methods it uses.
+func NewRooted(dir string) (*Rooted, error) {
+	root, err := os.OpenRoot(dir)
+	if err != nil {
+		return nil, err
+	}
+	d, err := root.Open(".")
+	if err != nil {
+		root.Close()
+		return nil, err
+	}
+	r := &Rooted{root: root, dir: d}
+	r.probe()
+	return r, nil
+}
+
+// Close releases the root directory.
+func (r *Rooted) Close() error {
+	err := r.dir.Close()
+	if errR := r.root.Close(); err == nil {
+		err = errR
+	}
+	return err
+}
+
+func (r *Rooted) Open(name string) (fs.File, error) {
+	return r.OpenFile(name, os.O_RDONLY, 0)
+}
+
+func (r *Rooted) Create(name string) (fs.File, error) {
+	return r.OpenFile(name, os.O_RDWR|os.O_CREATE|os.O_TRUNC, 0666)
+}
+
+func (r *Rooted) OpenFile(name string, flag int, perm os.FileMode) (fs.File, error) {
+	f, err := r.openFile(fsutil.CleanRelative(name), flag, perm)
+	if err != nil {
+		return nil, err
+	}
+	return file{f}, nil
+}
+
+func (r *Rooted) IsNotExist(err error) bool {
+	return fsutil.IsNotExist(err)
+}
+
+func (r *Rooted) Stat(name string) (os.FileInfo, error) {
+	return r.stat(fsutil.CleanRelative(name), true)
+}
+
+func (r *Rooted) Lstat(name string) (os.FileInfo, error) {
+	return r.stat(fsutil.CleanRelative(name), false)
+}
+
+func (r *Rooted) ReadDir(name string) ([]os.FileInfo, error) {
+	f, err := r.openFile(fsutil.CleanRelative(name), os.O_RDONLY, 0)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	infos, err := f.Readdir(-1)
+	if err != nil {
+		return nil, err
+	}
+	fsutil.SortByName(infos)
+	return infos, nil
+}
+
+func (r *Rooted) ReadFile(name string) ([]byte, error) {
+	f, err := r.openFile(fsutil.CleanRelative(name), os.O_RDONLY, 0)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return ioutil.ReadAll(f)
+}
+
+func (r *Rooted) WriteFile(name string, data []byte, perm os.FileMode) error {
+	f, err := r.openFile(fsutil.CleanRelative(name), os.O_WRONLY|os.O_CREATE|os.O_TRUNC, perm)
+	if err != nil {
+		return err
+	}
+	_, err = f.Write(data)
+	if errC := f.Close(); err == nil {
+		err = errC
+	}
+	return err
+}
+
+// Symlink stores oldname as is, it is resolved beneath the root when the link
+// is followed.
+func (r *Rooted) Symlink(oldname, newname string) error {
+	return r.root.Symlink(oldname, fsutil.CleanRelative(newname))
+}
+
+func (r *Rooted) Readlink(name string) (string, error) {
+	return r.root.Readlink(fsutil.CleanRelative(name))
+}
+
+func (r *Rooted) Chmod(name string, mode os.FileMode) error {
+	return r.root.Chmod(fsutil.CleanRelative(name), mode)
+}
+
+func (r *Rooted) Chown(name string, uid, gid int) error {
+	return r.root.Chown(fsutil.CleanRelative(name), uid, gid)
+}
+
+func (r *Rooted) Chtimes(name string, atime time.Time, mtime time.Time) error {
+	return r.root.Chtimes(fsutil.CleanRelative(name), atime, mtime)
+}
+
+func (r *Rooted) Ident(name string) (fs.Ident, error) {
+	fi, err := r.Stat(name)
+	if err != nil {
+		return fs.Ident{}, err
+	}
+	return infoIdent(name, fi)
+}
+
+// Times reports the same timestamps as the System from New, by using the file
+// descriptor of the file opened beneath the root.
+func (r *Rooted) Times(name string) (fs.Times, error) {
+	return r.times(fsutil.CleanRelative(name))
+}
+
+func (r *Rooted) Truncate(name string, size int64) error {
+	f, err := r.openFile(fsutil.CleanRelative(name), os.O_WRONLY, 0)
+	if err != nil {
+		return err
+	}
+	err = f.Truncate(size)
+	if errC := f.Close(); err == nil {
+		err = errC
+	}
+	return err
+}
+
+func (r *Rooted) Mkdir(name string, perm os.FileMode) error {
+	return r.root.Mkdir(fsutil.CleanRelative(name), perm)
+}
+
+func (r *Rooted) MkdirAll(path string, perm os.FileMode) error {
+	return r.root.MkdirAll(fsutil.CleanRelative(path), perm)
+}
+
+func (r *Rooted) Remove(name string) error {
+	return r.root.Remove(fsutil.CleanRelative(name))
+}
+
+func (r *Rooted) RemoveAll(path string) error {
+	return r.root.RemoveAll(fsutil.CleanRelative(path))
+}
+
+func (r *Rooted) Rename(oldname, newname string) error {
+	return r.root.Rename(fsutil.CleanRelative(oldname), fsutil.CleanRelative(newname))
+}
+
+// Opens the file using the os.Root.
+func (r *Rooted) openFileRoot(name string, flag int, perm os.FileMode) (*os.File, error) {
+	return r.root.OpenFile(name, flag, perm)
+}
+
+// Reports the timestamps of the file opened using the os.Root.
+func (r *Rooted) timesRoot(name string) (fs.Times, error) {
+	f, err := r.root.Open(name)
+	if err != nil {
+		return fs.Times{}, err
+	}
+	defer f.Close()
+	return file{f}.Times()
+}
+
+// Describes the file using the os.Root.
+func (r *Rooted) statRoot(name string, follow bool) (os.FileInfo, error) {
+	if follow {
+		return r.root.Stat(name)
+	}
+	return r.root.Lstat(name)
+}
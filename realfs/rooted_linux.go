@@ -0,0 +1,127 @@
+//go:build linux
+
+package realfs
+
+import (
+	"os"
+	"syscall"
+	"unsafe"
+
+	"github.com/daaku/go.fs"
+)
+
+// Constants for openat2, which the syscall package does not provide. The
+// system call number is the same on all architectures.
+const (
+	sysOpenat2          = 437
+	oPath               = 0x200000 // O_PATH
+	resolveNoMagiclinks = 0x02     // RESOLVE_NO_MAGICLINKS
+	resolveBeneath      = 0x08     // RESOLVE_BENEATH
+)
+
+// Matches struct open_how from linux/openat2.h.
+type openHow struct {
+	Flags   uint64
+	Mode    uint64
+	Resolve uint64
+}
+
+// Checks if openat2 can be used, which it can't if it is missing (ENOSYS),
+// blocked by a seccomp filter (EPERM), or if the kernel doesn't know the
+// RESOLVE flags (EINVAL). The os.Root is used instead in that case.
+func (r *Rooted) probe() {
+	f, err := r.openat2(".", oPath, 0)
+	switch err {
+	case nil:
+		f.Close()
+	case syscall.ENOSYS, syscall.EPERM, syscall.EINVAL:
+		r.noOpenat2 = true
+	}
+}
+
+func (r *Rooted) openFile(name string, flag int, perm os.FileMode) (*os.File, error) {
+	if r.noOpenat2 {
+		return r.openFileRoot(name, flag, perm)
+	}
+	f, err := r.openat2(name, flag, perm)
+	if err != nil {
+		return nil, &os.PathError{Op: "open", Path: name, Err: err}
+	}
+	return f, nil
+}
+
+// Describes the file by opening it with O_PATH, which does not require any
+// permissions on the file itself.
+func (r *Rooted) stat(name string, follow bool) (os.FileInfo, error) {
+	if r.noOpenat2 {
+		return r.statRoot(name, follow)
+	}
+	flag := oPath
+	if !follow {
+		flag |= syscall.O_NOFOLLOW
+	}
+	f, err := r.openat2(name, flag, 0)
+	if err != nil {
+		return nil, &os.PathError{Op: "stat", Path: name, Err: err}
+	}
+	defer f.Close()
+	return f.Stat()
+}
+
+// Reports the timestamps of the file opened with O_PATH, like stat.
+func (r *Rooted) times(name string) (fs.Times, error) {
+	if r.noOpenat2 {
+		return r.timesRoot(name)
+	}
+	f, err := r.openat2(name, oPath, 0)
+	if err != nil {
+		return fs.Times{}, &os.PathError{Op: "stat", Path: name, Err: err}
+	}
+	defer f.Close()
+	return file{f}.Times()
+}
+
+// Opens the file beneath the root directory. Escaping the root using ".." or
+// a symbolic link fails with EXDEV.
+func (r *Rooted) openat2(name string, flag int, perm os.FileMode) (*os.File, error) {
+	p, err := syscall.BytePtrFromString(name)
+	if err != nil {
+		return nil, err
+	}
+	how := openHow{
+		Flags:   uint64(flag | syscall.O_CLOEXEC | syscall.O_LARGEFILE),
+		Resolve: resolveBeneath | resolveNoMagiclinks,
+	}
+	// the mode must be zero unless a file is being created
+	if flag&os.O_CREATE != 0 {
+		how.Mode = uint64(unixMode(perm))
+	}
+	for {
+		fd, _, errno := syscall.Syscall6(sysOpenat2, r.dir.Fd(),
+			uintptr(unsafe.Pointer(p)), uintptr(unsafe.Pointer(&how)),
+			unsafe.Sizeof(how), 0, 0)
+		switch errno {
+		case 0:
+			return os.NewFile(fd, name), nil
+		case syscall.EINTR:
+			continue
+		default:
+			return nil, errno
+		}
+	}
+}
+
+// Converts the permission bits of an os.FileMode to the unix ones.
+func unixMode(perm os.FileMode) uint32 {
+	mode := uint32(perm.Perm())
+	if perm&os.ModeSetuid != 0 {
+		mode |= syscall.S_ISUID
+	}
+	if perm&os.ModeSetgid != 0 {
+		mode |= syscall.S_ISGID
+	}
+	if perm&os.ModeSticky != 0 {
+		mode |= syscall.S_ISVTX
+	}
+	return mode
+}
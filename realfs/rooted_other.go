@@ -0,0 +1,23 @@
+//go:build !linux
+
+package realfs
+
+import (
+	"os"
+
+	"github.com/daaku/go.fs"
+)
+
+func (r *Rooted) probe() {}
+
+func (r *Rooted) openFile(name string, flag int, perm os.FileMode) (*os.File, error) {
+	return r.openFileRoot(name, flag, perm)
+}
+
+func (r *Rooted) times(name string) (fs.Times, error) {
+	return r.timesRoot(name)
+}
+
+func (r *Rooted) stat(name string, follow bool) (os.FileInfo, error) {
+	return r.statRoot(name, follow)
+}
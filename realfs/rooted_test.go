@@ -0,0 +1,214 @@
+package realfs_test
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+	"time"
+
+	"github.com/daaku/go.fs"
+	"github.com/daaku/go.fs/fstest"
+	"github.com/daaku/go.fs/fsutil"
+	"github.com/daaku/go.fs/realfs"
+)
+
+// The ways of creating a Rooted System, so the os.Root fallback is tested even
+// where openat2 is available.
+var rootedKinds = map[string]func(dir string) (*realfs.Rooted, error){
+	"default":        realfs.NewRooted,
+	"withoutOpenat2": realfs.NewRootedWithoutOpenat2,
+}
+
+// Creates a directory with a root directory in it, along with a secret file
+// outside of the root.
+func newRooted(t *testing.T) (fs.System, string) {
+	return newRootedKind(t, realfs.NewRooted)
+}
+
+func newRootedKind(t *testing.T, newRooted func(string) (*realfs.Rooted, error)) (fs.System, string) {
+	dir, err := ioutil.TempDir("", "realfs_test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.RemoveAll(dir) })
+	if err := ioutil.WriteFile(filepath.Join(dir, "secret"), []byte("secret"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	root := filepath.Join(dir, "root")
+	if err := os.Mkdir(root, 0755); err != nil {
+		t.Fatal(err)
+	}
+	s, err := newRooted(root)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { s.Close() })
+	return s, root
+}
+
+func TestRootedConformance(t *testing.T) {
+	t.Parallel()
+	for kind, newRooted := range rootedKinds {
+		newRooted := newRooted
+		t.Run(kind, func(t *testing.T) {
+			t.Parallel()
+			fstest.TestSystem(t, fstest.Config{
+				New: func(t *testing.T, files map[string]string) (fs.System, string) {
+					s, _ := newRootedKind(t, newRooted)
+					if err := fstest.Populate(s, "/", files); err != nil {
+						t.Fatal(err)
+					}
+					return s, "/"
+				},
+			})
+		})
+	}
+}
+
+func TestRootedNames(t *testing.T) {
+	t.Parallel()
+	s, root := newRooted(t)
+	if err := ioutil.WriteFile(filepath.Join(root, "foo"), []byte("foo"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	for _, name := range []string{"foo", "/foo", "../foo", "/../../foo"} {
+		b, err := fsutil.ReadFile(s, name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(b) != "foo" {
+			t.Fatalf("%s: was expecting foo, got %s", name, b)
+		}
+	}
+	if _, err := s.Open("../secret"); !s.IsNotExist(err) {
+		t.Fatalf("was expecting is not exist error, got %v", err)
+	}
+}
+
+func TestRootedSymlinkEscape(t *testing.T) {
+	t.Parallel()
+	for kind, newRooted := range rootedKinds {
+		newRooted := newRooted
+		t.Run(kind, func(t *testing.T) {
+			t.Parallel()
+			testRootedSymlinkEscape(t, newRooted)
+		})
+	}
+}
+
+func testRootedSymlinkEscape(t *testing.T, newRooted func(string) (*realfs.Rooted, error)) {
+	s, root := newRootedKind(t, newRooted)
+	secret := filepath.Join(filepath.Dir(root), "secret")
+	links := map[string]string{
+		"absolute": secret,
+		"relative": "../secret",
+		"dir":      "..",
+	}
+	for name, target := range links {
+		if err := os.Symlink(target, filepath.Join(root, name)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for _, name := range []string{"absolute", "relative", "dir/secret"} {
+		if _, err := s.Open(name); err == nil {
+			t.Fatalf("%s: was expecting an error", name)
+		}
+		if _, err := fsutil.Stat(s, name); err == nil {
+			t.Fatalf("%s: was expecting an error", name)
+		}
+		if err := fsutil.WriteFile(s, name, []byte("pwned"), 0644); err == nil {
+			t.Fatalf("%s: was expecting an error", name)
+		}
+		if err := fsutil.Chmod(s, name, 0777); err == nil {
+			t.Fatalf("%s: was expecting an error", name)
+		}
+	}
+	if err := s.Mkdir("dir/escaped", 0755); err == nil {
+		t.Fatal("was expecting an error")
+	}
+	b, err := ioutil.ReadFile(secret)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(b) != "secret" {
+		t.Fatalf("was expecting the secret to be untouched, got %s", b)
+	}
+
+	// the links themselves can be inspected
+	fi, err := fsutil.Lstat(s, "absolute")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fi.Mode()&os.ModeSymlink == 0 {
+		t.Fatalf("was expecting a symlink, got %v", fi.Mode())
+	}
+}
+
+func TestRootedSymlinkInside(t *testing.T) {
+	t.Parallel()
+	s, _ := newRooted(t)
+	if err := s.MkdirAll("a/b", 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := fsutil.WriteFile(s, "a/b/foo", []byte("foo"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := fsutil.Symlink(s, "../a/b/foo", "a/link"); err != nil {
+		t.Fatal(err)
+	}
+	b, err := fsutil.ReadFile(s, "a/link")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(b) != "foo" {
+		t.Fatalf("was expecting foo, got %s", b)
+	}
+}
+
+func TestRootedTimes(t *testing.T) {
+	t.Parallel()
+	for kind, newRooted := range rootedKinds {
+		newRooted := newRooted
+		t.Run(kind, func(t *testing.T) {
+			t.Parallel()
+			testRootedTimes(t, newRooted)
+		})
+	}
+}
+
+func testRootedTimes(t *testing.T, newRooted func(string) (*realfs.Rooted, error)) {
+	s, root := newRootedKind(t, newRooted)
+	name := filepath.Join(root, "foo")
+	if err := ioutil.WriteFile(name, []byte("foo"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	atime := time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC)
+	mtime := atime.Add(time.Hour)
+	if err := os.Chtimes(name, atime, mtime); err != nil {
+		t.Fatal(err)
+	}
+	times, err := fsutil.Times(s, "foo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !times.Mtime.Equal(mtime) {
+		t.Fatalf("was expecting mtime %v, got %v", mtime, times.Mtime)
+	}
+	if runtime.GOOS == "linux" {
+		if !times.Atime.Equal(atime) || times.Ctime.Before(mtime) {
+			t.Fatalf("did not find expected times, got %v", times)
+		}
+	}
+	if _, err := fsutil.Times(s, "../secret"); !s.IsNotExist(err) {
+		t.Fatalf("was expecting is not exist error, got %v", err)
+	}
+}
+
+func TestRootedNotExist(t *testing.T) {
+	t.Parallel()
+	if _, err := realfs.NewRooted("/foo/bar/baz/boom"); !os.IsNotExist(err) {
+		t.Fatalf("was expecting is not exist error, got %v", err)
+	}
+}
@@ -0,0 +1,160 @@
+//go:build linux
+
+package realfs
+
+import (
+	"errors"
+	"os"
+	"runtime"
+	"syscall"
+	"time"
+	"unsafe"
+
+	"github.com/daaku/go.fs"
+)
+
+// The statx system call number, which the syscall package does not provide.
+// Architectures not listed here fall back to stat.
+var sysStatx = map[string]uintptr{
+	"386":     383,
+	"amd64":   332,
+	"arm":     397,
+	"arm64":   291,
+	"loong64": 291,
+	"ppc64":   383,
+	"ppc64le": 383,
+	"riscv64": 291,
+	"s390x":   379,
+}[runtime.GOARCH]
+
+const (
+	atFDCWD         = -100
+	atEmptyPath     = 0x1000
+	statxBasicStats = 0x7ff
+	statxBtime      = 0x800
+)
+
+type statxTimestamp struct {
+	Sec  int64
+	Nsec uint32
+	_    int32
+}
+
+func (ts statxTimestamp) time() time.Time {
+	return time.Unix(ts.Sec, int64(ts.Nsec))
+}
+
+// Matches struct statx from linux/stat.h.
+type statxT struct {
+	Mask           uint32
+	Blksize        uint32
+	Attributes     uint64
+	Nlink          uint32
+	Uid            uint32
+	Gid            uint32
+	Mode           uint16
+	_              uint16
+	Ino            uint64
+	Size           uint64
+	Blocks         uint64
+	AttributesMask uint64
+	Atime          statxTimestamp
+	Btime          statxTimestamp
+	Ctime          statxTimestamp
+	Mtime          statxTimestamp
+	RdevMajor      uint32
+	RdevMinor      uint32
+	DevMajor       uint32
+	DevMinor       uint32
+	_              [14]uint64
+}
+
+// Calls statx for the named file relative to dirfd, returning syscall.ENOSYS
+// if it is not available.
+func statx(dirfd int, name string, flags int) (*statxT, error) {
+	if sysStatx == 0 {
+		return nil, syscall.ENOSYS
+	}
+	p, err := syscall.BytePtrFromString(name)
+	if err != nil {
+		return nil, err
+	}
+	var stx statxT
+	for {
+		_, _, errno := syscall.Syscall6(sysStatx, uintptr(dirfd),
+			uintptr(unsafe.Pointer(p)), uintptr(flags), statxBasicStats|statxBtime,
+			uintptr(unsafe.Pointer(&stx)), 0)
+		switch errno {
+		case 0:
+			return &stx, nil
+		case syscall.EINTR:
+			continue
+		default:
+			return nil, errno
+		}
+	}
+}
+
+// Returns the timestamps reported by statx.
+func (stx *statxT) times() fs.Times {
+	times := fs.Times{
+		Atime: stx.Atime.time(),
+		Mtime: stx.Mtime.time(),
+		Ctime: stx.Ctime.time(),
+	}
+	// some file systems claim to support it but report zero
+	if stx.Mask&statxBtime != 0 && stx.Btime != (statxTimestamp{}) {
+		times.Btime = stx.Btime.time()
+	}
+	return times
+}
+
+// Times uses statx, which also reports the birth time if the underlying file
+// system records it. It falls back to stat on older kernels.
+func (s system) Times(name string) (fs.Times, error) {
+	stx, err := statx(atFDCWD, name, 0)
+	if err == syscall.ENOSYS {
+		fi, err := os.Stat(name)
+		if err != nil {
+			return fs.Times{}, err
+		}
+		return statTimes(fi), nil
+	}
+	if err != nil {
+		return fs.Times{}, &fs.PathError{Op: "statx", Path: name, Err: err}
+	}
+	return stx.times(), nil
+}
+
+// Times uses statx on the open file, so it describes the file even if it has
+// since been renamed. It falls back to fstat on older kernels.
+func (f file) Times() (fs.Times, error) {
+	var stx *statxT
+	err := f.control("statx", func(fd uintptr) error {
+		var err error
+		stx, err = statx(int(fd), "", atEmptyPath)
+		return err
+	})
+	if errors.Is(err, syscall.ENOSYS) {
+		fi, err := f.Stat()
+		if err != nil {
+			return fs.Times{}, err
+		}
+		return statTimes(fi), nil
+	}
+	if err != nil {
+		return fs.Times{}, err
+	}
+	return stx.times(), nil
+}
+
+// Returns the times from the *syscall.Stat_t of the FileInfo, which does not
+// include the birth time.
+func statTimes(fi os.FileInfo) fs.Times {
+	st := fi.Sys().(*syscall.Stat_t)
+	return fs.Times{
+		Atime: time.Unix(st.Atim.Unix()),
+		Mtime: fi.ModTime(),
+		Ctime: time.Unix(st.Ctim.Unix()),
+	}
+}
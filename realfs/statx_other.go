@@ -0,0 +1,26 @@
+//go:build !linux
+
+package realfs
+
+import (
+	"os"
+
+	"github.com/daaku/go.fs"
+)
+
+// Times only reports the modification time outside of Linux.
+func (s system) Times(name string) (fs.Times, error) {
+	fi, err := os.Stat(name)
+	if err != nil {
+		return fs.Times{}, err
+	}
+	return fs.Times{Mtime: fi.ModTime()}, nil
+}
+
+func (f file) Times() (fs.Times, error) {
+	fi, err := f.Stat()
+	if err != nil {
+		return fs.Times{}, err
+	}
+	return fs.Times{Mtime: fi.ModTime()}, nil
+}
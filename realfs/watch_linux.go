@@ -0,0 +1,207 @@
+//go:build linux
+
+package realfs
+
+import (
+	"encoding/binary"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"syscall"
+
+	"github.com/daaku/go.fs"
+)
+
+const watchMask = syscall.IN_CREATE | syscall.IN_MODIFY | syscall.IN_DELETE |
+	syscall.IN_DELETE_SELF | syscall.IN_MOVED_FROM | syscall.IN_MOVED_TO |
+	syscall.IN_MOVE_SELF | syscall.IN_ATTRIB
+
+// Watch uses inotify. Recursive watches add a watch for every nested
+// directory, including ones created after the Watcher was started.
+func (s system) Watch(name string, recursive bool) (fs.Watcher, error) {
+	fd, err := syscall.InotifyInit1(syscall.IN_CLOEXEC | syscall.IN_NONBLOCK)
+	if err != nil {
+		return nil, &fs.PathError{Op: "watch", Path: name, Err: err}
+	}
+	w := &watcher{
+		fd:        fd,
+		file:      os.NewFile(uintptr(fd), "inotify"),
+		name:      name,
+		recursive: recursive,
+		paths:     make(map[int32]string),
+		events:    make(chan fs.Event),
+		errors:    make(chan error),
+		done:      make(chan struct{}),
+	}
+	if err := w.add(name); err != nil {
+		w.file.Close()
+		return nil, err
+	}
+	go w.run()
+	return w, nil
+}
+
+type watcher struct {
+	fd        int
+	file      *os.File // wraps fd to allow Close to interrupt a blocked Read
+	name      string
+	recursive bool
+	paths     map[int32]string // watch descriptor to path, only used by run
+	events    chan fs.Event
+	errors    chan error
+	done      chan struct{}
+	closeOnce sync.Once
+}
+
+func (w *watcher) Events() <-chan fs.Event {
+	return w.events
+}
+
+func (w *watcher) Errors() <-chan error {
+	return w.errors
+}
+
+func (w *watcher) Close() error {
+	var err error
+	w.closeOnce.Do(func() {
+		close(w.done)
+		err = w.file.Close()
+	})
+	return err
+}
+
+// Adds a watch for the named path, and for recursive watches also for all
+// the directories nested within it.
+func (w *watcher) add(name string) error {
+	wd, err := syscall.InotifyAddWatch(w.fd, name, watchMask)
+	if err != nil {
+		return &fs.PathError{Op: "watch", Path: name, Err: err}
+	}
+	w.paths[int32(wd)] = name
+	if !w.recursive {
+		return nil
+	}
+	infos, err := readDirIfDir(name)
+	if err != nil {
+		return err
+	}
+	for _, fi := range infos {
+		if fi.IsDir() {
+			if err := w.add(filepath.Join(name, fi.Name())); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func (w *watcher) run() {
+	defer close(w.errors)
+	defer close(w.events)
+	buf := make([]byte, 64*1024)
+	for {
+		n, err := w.file.Read(buf)
+		if err != nil {
+			if !errors.Is(err, os.ErrClosed) {
+				w.sendError(err)
+			}
+			return
+		}
+		for off := 0; off+syscall.SizeofInotifyEvent <= n; {
+			wd := int32(binary.NativeEndian.Uint32(buf[off:]))
+			mask := binary.NativeEndian.Uint32(buf[off+4:])
+			length := int(binary.NativeEndian.Uint32(buf[off+12:]))
+			off += syscall.SizeofInotifyEvent
+			name := strings.TrimRight(string(buf[off:off+length]), "\x00")
+			off += length
+			if !w.handle(wd, mask, name) {
+				return
+			}
+		}
+	}
+}
+
+// Handles a single inotify event, returning false if the Watcher was closed.
+func (w *watcher) handle(wd int32, mask uint32, name string) bool {
+	if mask&syscall.IN_Q_OVERFLOW != 0 {
+		return w.sendError(fs.ErrEventOverflow)
+	}
+	dir, ok := w.paths[wd]
+	if !ok {
+		return true
+	}
+	if mask&syscall.IN_IGNORED != 0 {
+		delete(w.paths, wd)
+		return true
+	}
+	full := dir
+	if name != "" {
+		full = filepath.Join(dir, name)
+	}
+
+	// nested directories also report the event to their parent, so only the
+	// root reports events about itself
+	if mask&(syscall.IN_DELETE_SELF|syscall.IN_MOVE_SELF) != 0 && dir != w.name {
+		return true
+	}
+
+	if w.recursive && mask&syscall.IN_ISDIR != 0 &&
+		mask&(syscall.IN_CREATE|syscall.IN_MOVED_TO) != 0 {
+		if err := w.add(full); err != nil && !os.IsNotExist(err) {
+			if !w.sendError(err) {
+				return false
+			}
+		}
+	}
+
+	var op fs.Op
+	if mask&(syscall.IN_CREATE|syscall.IN_MOVED_TO) != 0 {
+		op |= fs.OpCreate
+	}
+	if mask&syscall.IN_MODIFY != 0 {
+		op |= fs.OpWrite
+	}
+	if mask&(syscall.IN_DELETE|syscall.IN_DELETE_SELF) != 0 {
+		op |= fs.OpRemove
+	}
+	if mask&(syscall.IN_MOVED_FROM|syscall.IN_MOVE_SELF) != 0 {
+		op |= fs.OpRename
+	}
+	if mask&syscall.IN_ATTRIB != 0 {
+		op |= fs.OpChmod
+	}
+	if op == 0 {
+		return true
+	}
+	select {
+	case w.events <- fs.Event{Name: full, Op: op}:
+		return true
+	case <-w.done:
+		return false
+	}
+}
+
+func (w *watcher) sendError(err error) bool {
+	select {
+	case w.errors <- err:
+		return true
+	case <-w.done:
+		return false
+	}
+}
+
+// Returns the directory listing, or nothing if name is not a directory.
+func readDirIfDir(name string) ([]os.FileInfo, error) {
+	fi, err := os.Stat(name)
+	if err != nil || !fi.IsDir() {
+		return nil, err
+	}
+	f, err := os.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return f.Readdir(-1)
+}
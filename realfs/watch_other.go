@@ -0,0 +1,12 @@
+//go:build !linux
+
+package realfs
+
+import (
+	"github.com/daaku/go.fs"
+)
+
+// Watch is only supported on Linux.
+func (s system) Watch(name string, recursive bool) (fs.Watcher, error) {
+	return nil, &fs.PathError{Op: "watch", Path: name, Err: fs.ErrNotSupported}
+}
@@ -0,0 +1,168 @@
+//go:build linux
+
+package realfs
+
+import (
+	"strings"
+	"syscall"
+	"unsafe"
+
+	"github.com/daaku/go.fs"
+)
+
+func (s system) Getxattr(name, attr string) ([]byte, error) {
+	for {
+		size, err := syscall.Getxattr(name, attr, nil)
+		if err != nil {
+			return nil, &fs.PathError{Op: "getxattr", Path: name, Err: err}
+		}
+		buf := make([]byte, size)
+		n, err := syscall.Getxattr(name, attr, buf)
+		if err == syscall.ERANGE {
+			continue // grew between the calls
+		}
+		if err != nil {
+			return nil, &fs.PathError{Op: "getxattr", Path: name, Err: err}
+		}
+		return buf[:n], nil
+	}
+}
+
+func (s system) Setxattr(name, attr string, data []byte) error {
+	if err := syscall.Setxattr(name, attr, data, 0); err != nil {
+		return &fs.PathError{Op: "setxattr", Path: name, Err: err}
+	}
+	return nil
+}
+
+func (s system) Listxattr(name string) ([]string, error) {
+	for {
+		size, err := syscall.Listxattr(name, nil)
+		if err != nil {
+			return nil, &fs.PathError{Op: "listxattr", Path: name, Err: err}
+		}
+		if size == 0 {
+			return nil, nil
+		}
+		buf := make([]byte, size)
+		n, err := syscall.Listxattr(name, buf)
+		if err == syscall.ERANGE {
+			continue // grew between the calls
+		}
+		if err != nil {
+			return nil, &fs.PathError{Op: "listxattr", Path: name, Err: err}
+		}
+		return strings.Split(strings.TrimSuffix(string(buf[:n]), "\x00"), "\x00"), nil
+	}
+}
+
+func (s system) Removexattr(name, attr string) error {
+	if err := syscall.Removexattr(name, attr); err != nil {
+		return &fs.PathError{Op: "removexattr", Path: name, Err: err}
+	}
+	return nil
+}
+
+// The File methods use the f* variants of the system calls, so they apply to
+// the open file even if it has since been renamed.
+
+func (f file) Getxattr(attr string) ([]byte, error) {
+	var data []byte
+	err := f.control("getxattr", func(fd uintptr) error {
+		for {
+			size, err := fxattr(syscall.SYS_FGETXATTR, fd, attr, nil)
+			if err != nil {
+				return err
+			}
+			buf := make([]byte, size)
+			n, err := fxattr(syscall.SYS_FGETXATTR, fd, attr, buf)
+			if err == syscall.ERANGE {
+				continue // grew between the calls
+			}
+			if err != nil {
+				return err
+			}
+			data = buf[:n]
+			return nil
+		}
+	})
+	return data, err
+}
+
+func (f file) Setxattr(attr string, data []byte) error {
+	return f.control("setxattr", func(fd uintptr) error {
+		_, err := fxattr(syscall.SYS_FSETXATTR, fd, attr, data)
+		return err
+	})
+}
+
+func (f file) Listxattr() ([]string, error) {
+	var names []string
+	err := f.control("listxattr", func(fd uintptr) error {
+		for {
+			size, err := flistxattr(fd, nil)
+			if err != nil || size == 0 {
+				return err
+			}
+			buf := make([]byte, size)
+			n, err := flistxattr(fd, buf)
+			if err == syscall.ERANGE {
+				continue // grew between the calls
+			}
+			if err != nil {
+				return err
+			}
+			names = strings.Split(strings.TrimSuffix(string(buf[:n]), "\x00"), "\x00")
+			return nil
+		}
+	})
+	return names, err
+}
+
+func (f file) Removexattr(attr string) error {
+	return f.control("removexattr", func(fd uintptr) error {
+		p, err := syscall.BytePtrFromString(attr)
+		if err != nil {
+			return err
+		}
+		_, _, errno := syscall.Syscall(syscall.SYS_FREMOVEXATTR, fd,
+			uintptr(unsafe.Pointer(p)), 0)
+		if errno != 0 {
+			return errno
+		}
+		return nil
+	})
+}
+
+// Calls fgetxattr or fsetxattr, which take the same arguments, returning the
+// size of the value for fgetxattr.
+func fxattr(trap, fd uintptr, attr string, buf []byte) (int, error) {
+	p, err := syscall.BytePtrFromString(attr)
+	if err != nil {
+		return 0, err
+	}
+	var b unsafe.Pointer
+	if len(buf) > 0 {
+		b = unsafe.Pointer(&buf[0])
+	}
+	n, _, errno := syscall.Syscall6(trap, fd, uintptr(unsafe.Pointer(p)),
+		uintptr(b), uintptr(len(buf)), 0, 0)
+	if errno != 0 {
+		return 0, errno
+	}
+	return int(n), nil
+}
+
+// Calls flistxattr, returning the size of the list.
+func flistxattr(fd uintptr, buf []byte) (int, error) {
+	var b unsafe.Pointer
+	if len(buf) > 0 {
+		b = unsafe.Pointer(&buf[0])
+	}
+	n, _, errno := syscall.Syscall(syscall.SYS_FLISTXATTR, fd, uintptr(b),
+		uintptr(len(buf)))
+	if errno != 0 {
+		return 0, errno
+	}
+	return int(n), nil
+}
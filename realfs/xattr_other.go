@@ -0,0 +1,40 @@
+//go:build !linux
+
+package realfs
+
+import (
+	"github.com/daaku/go.fs"
+)
+
+// Extended attributes are only supported on Linux.
+func (s system) Getxattr(name, attr string) ([]byte, error) {
+	return nil, &fs.PathError{Op: "getxattr", Path: name, Err: fs.ErrNotSupported}
+}
+
+func (s system) Setxattr(name, attr string, data []byte) error {
+	return &fs.PathError{Op: "setxattr", Path: name, Err: fs.ErrNotSupported}
+}
+
+func (s system) Listxattr(name string) ([]string, error) {
+	return nil, &fs.PathError{Op: "listxattr", Path: name, Err: fs.ErrNotSupported}
+}
+
+func (s system) Removexattr(name, attr string) error {
+	return &fs.PathError{Op: "removexattr", Path: name, Err: fs.ErrNotSupported}
+}
+
+func (f file) Getxattr(attr string) ([]byte, error) {
+	return nil, &fs.PathError{Op: "getxattr", Path: f.Name(), Err: fs.ErrNotSupported}
+}
+
+func (f file) Setxattr(attr string, data []byte) error {
+	return &fs.PathError{Op: "setxattr", Path: f.Name(), Err: fs.ErrNotSupported}
+}
+
+func (f file) Listxattr() ([]string, error) {
+	return nil, &fs.PathError{Op: "listxattr", Path: f.Name(), Err: fs.ErrNotSupported}
+}
+
+func (f file) Removexattr(attr string) error {
+	return &fs.PathError{Op: "removexattr", Path: f.Name(), Err: fs.ErrNotSupported}
+}
@@ -0,0 +1,318 @@
+// Package stdfs bridges File Systems and the standard library io/fs package.
+//
+// FS exposes any fs.System as an io/fs.FS, which allows using it with things
+// like html/template.ParseFS and http.FS. New does the reverse, and wraps an
+// io/fs.FS like embed.FS as a read-only fs.System.
+package stdfs
+
+import (
+	"errors"
+	"io"
+	iofs "io/fs"
+	"os"
+	"sort"
+
+	"github.com/daaku/go.fs"
+	"github.com/daaku/go.fs/fsutil"
+	"github.com/daaku/go.fs/limitfs"
+)
+
+type stdFS struct {
+	system fs.System
+}
+
+// FS returns an io/fs.FS view of the given System. Names are passed to the
+// System as is, so "." refers to the root directory. The returned value also
+// implements io/fs.ReadDirFS, io/fs.StatFS, io/fs.ReadFileFS, io/fs.GlobFS
+// and io/fs.SubFS.
+func FS(s fs.System) iofs.FS {
+	return stdFS{system: s}
+}
+
+func (f stdFS) Open(name string) (iofs.File, error) {
+	if !iofs.ValidPath(name) {
+		return nil, invalid("open", name)
+	}
+	file, err := f.system.Open(name)
+	if err != nil {
+		return nil, f.convert("open", name, err)
+	}
+	return &stdFile{File: file}, nil
+}
+
+func (f stdFS) Stat(name string) (iofs.FileInfo, error) {
+	if !iofs.ValidPath(name) {
+		return nil, invalid("stat", name)
+	}
+	fi, err := fsutil.Stat(f.system, name)
+	if err != nil {
+		return nil, f.convert("stat", name, err)
+	}
+	return fi, nil
+}
+
+func (f stdFS) ReadDir(name string) ([]iofs.DirEntry, error) {
+	if !iofs.ValidPath(name) {
+		return nil, invalid("readdir", name)
+	}
+	infos, err := fsutil.ReadDir(f.system, name)
+	if err != nil {
+		return nil, f.convert("readdir", name, err)
+	}
+	entries := make([]iofs.DirEntry, len(infos))
+	for ix, fi := range infos {
+		entries[ix] = iofs.FileInfoToDirEntry(fi)
+	}
+	return entries, nil
+}
+
+func (f stdFS) ReadFile(name string) ([]byte, error) {
+	if !iofs.ValidPath(name) {
+		return nil, invalid("readfile", name)
+	}
+	b, err := fsutil.ReadFile(f.system, name)
+	if err != nil {
+		return nil, f.convert("readfile", name, err)
+	}
+	return b, nil
+}
+
+func (f stdFS) Glob(pattern string) ([]string, error) {
+	return iofs.Glob(noGlob{f}, pattern)
+}
+
+// Sub uses limitfs to provide the view rooted at dir.
+func (f stdFS) Sub(dir string) (iofs.FS, error) {
+	if !iofs.ValidPath(dir) {
+		return nil, invalid("sub", dir)
+	}
+	if dir == "." {
+		return f, nil
+	}
+	fi, err := f.Stat(dir)
+	if err != nil {
+		return nil, err
+	}
+	if !fi.IsDir() {
+		return nil, &iofs.PathError{Op: "sub", Path: dir, Err: fs.ErrNotDir}
+	}
+	return FS(limitfs.New(limitfs.Config{Root: dir, Recursive: true}, f.system)), nil
+}
+
+// Converts errors from the System to ones that work with errors.Is and the
+// io/fs sentinel errors.
+func (f stdFS) convert(op, name string, err error) error {
+	if f.system.IsNotExist(err) && !errors.Is(err, iofs.ErrNotExist) {
+		return &iofs.PathError{Op: op, Path: name, Err: iofs.ErrNotExist}
+	}
+	return err
+}
+
+func invalid(op, name string) error {
+	return &iofs.PathError{Op: op, Path: name, Err: iofs.ErrInvalid}
+}
+
+// Hides the Glob method to use the generic implementation in io/fs.
+type noGlob struct {
+	iofs.ReadDirFS
+}
+
+type stdFile struct {
+	fs.File
+	entries []iofs.DirEntry // directory listing, loaded on first ReadDir
+	off     int
+}
+
+// ReadDir loads the entire directory listing on the first call, and returns
+// the entries sorted by name.
+func (f *stdFile) ReadDir(n int) ([]iofs.DirEntry, error) {
+	if f.entries == nil {
+		infos, err := f.File.Readdir(-1)
+		if err != nil && err != io.EOF {
+			return nil, err
+		}
+		f.entries = make([]iofs.DirEntry, len(infos))
+		for ix, fi := range infos {
+			f.entries[ix] = iofs.FileInfoToDirEntry(fi)
+		}
+		sort.Slice(f.entries, func(i, j int) bool {
+			return f.entries[i].Name() < f.entries[j].Name()
+		})
+	}
+	rest := f.entries[f.off:]
+	if n <= 0 {
+		f.off = len(f.entries)
+		return rest, nil
+	}
+	if len(rest) == 0 {
+		return nil, io.EOF
+	}
+	if n > len(rest) {
+		n = len(rest)
+	}
+	f.off += n
+	return rest[:n], nil
+}
+
+type system struct {
+	fsys iofs.FS
+}
+
+// New returns a read-only System backed by the given io/fs.FS. Names are
+// cleaned to the unrooted form expected by io/fs, so "/dir/file" and
+// "dir/file" refer to the same file.
+func New(fsys iofs.FS) fs.System {
+	return system{fsys: fsys}
+}
+
+func (s system) Open(name string) (fs.File, error) {
+	f, err := s.fsys.Open(fsutil.CleanRelative(name))
+	if err != nil {
+		return nil, err
+	}
+	return &file{File: f, name: name}, nil
+}
+
+func (s system) Stat(name string) (os.FileInfo, error) {
+	return iofs.Stat(s.fsys, fsutil.CleanRelative(name))
+}
+
+func (s system) ReadDir(name string) ([]os.FileInfo, error) {
+	entries, err := iofs.ReadDir(s.fsys, fsutil.CleanRelative(name))
+	if err != nil {
+		return nil, err
+	}
+	infos := make([]os.FileInfo, 0, len(entries))
+	for _, e := range entries {
+		fi, err := e.Info()
+		if err != nil {
+			return nil, err
+		}
+		infos = append(infos, fi)
+	}
+	return infos, nil
+}
+
+func (s system) ReadFile(name string) ([]byte, error) {
+	return iofs.ReadFile(s.fsys, fsutil.CleanRelative(name))
+}
+
+func (s system) IsNotExist(err error) bool {
+	return errors.Is(err, iofs.ErrNotExist) || fsutil.IsNotExist(err)
+}
+
+func (s system) Create(name string) (fs.File, error) {
+	return nil, &fs.PathError{Op: "open", Path: name, Err: fs.ErrReadOnly}
+}
+
+func (s system) OpenFile(name string, flag int, perm os.FileMode) (fs.File, error) {
+	if fsutil.WantsWrite(flag) {
+		return nil, &fs.PathError{Op: "open", Path: name, Err: fs.ErrReadOnly}
+	}
+	return s.Open(name)
+}
+
+func (s system) Mkdir(name string, perm os.FileMode) error {
+	return &fs.PathError{Op: "mkdir", Path: name, Err: fs.ErrReadOnly}
+}
+
+func (s system) MkdirAll(path string, perm os.FileMode) error {
+	return &fs.PathError{Op: "mkdir", Path: path, Err: fs.ErrReadOnly}
+}
+
+func (s system) Remove(name string) error {
+	return &fs.PathError{Op: "remove", Path: name, Err: fs.ErrReadOnly}
+}
+
+func (s system) RemoveAll(path string) error {
+	return &fs.PathError{Op: "remove", Path: path, Err: fs.ErrReadOnly}
+}
+
+func (s system) Rename(oldname, newname string) error {
+	return &fs.LinkError{Op: "rename", Old: oldname, New: newname, Err: fs.ErrReadOnly}
+}
+
+type file struct {
+	iofs.File
+	name string
+}
+
+func (f *file) Chmod(mode os.FileMode) error {
+	return f.pathError("chmod", fs.ErrReadOnly)
+}
+
+func (f *file) Chown(uid, gid int) error {
+	return f.pathError("chown", fs.ErrReadOnly)
+}
+
+func (f *file) Ident() (fs.Ident, error) {
+	return fs.Ident{}, f.pathError("ident", fs.ErrNotSupported)
+}
+
+func (f *file) ReadAt(b []byte, off int64) (n int, err error) {
+	if r, ok := f.File.(io.ReaderAt); ok {
+		return r.ReadAt(b, off)
+	}
+	return 0, f.pathError("read", fs.ErrNotSupported)
+}
+
+func (f *file) Readdir(n int) ([]os.FileInfo, error) {
+	d, ok := f.File.(iofs.ReadDirFile)
+	if !ok {
+		return nil, f.pathError("readdir", fs.ErrNotDir)
+	}
+	entries, err := d.ReadDir(n)
+	infos := make([]os.FileInfo, 0, len(entries))
+	for _, e := range entries {
+		fi, errI := e.Info()
+		if errI != nil {
+			return infos, errI
+		}
+		infos = append(infos, fi)
+	}
+	return infos, err
+}
+
+func (f *file) Readdirnames(n int) (names []string, err error) {
+	d, ok := f.File.(iofs.ReadDirFile)
+	if !ok {
+		return nil, f.pathError("readdir", fs.ErrNotDir)
+	}
+	entries, err := d.ReadDir(n)
+	names = make([]string, len(entries))
+	for ix, e := range entries {
+		names[ix] = e.Name()
+	}
+	return names, err
+}
+
+func (f *file) Seek(offset int64, whence int) (ret int64, err error) {
+	if s, ok := f.File.(io.Seeker); ok {
+		return s.Seek(offset, whence)
+	}
+	return 0, f.pathError("seek", fs.ErrNotSupported)
+}
+
+func (f *file) Sync() error {
+	return nil
+}
+
+func (f *file) Truncate(size int64) error {
+	return f.pathError("truncate", fs.ErrReadOnly)
+}
+
+func (f *file) Write(b []byte) (ret int, err error) {
+	return 0, f.pathError("write", fs.ErrReadOnly)
+}
+
+func (f *file) WriteAt(b []byte, off int64) (ret int, err error) {
+	return 0, f.pathError("write", fs.ErrReadOnly)
+}
+
+func (f *file) WriteString(s string) (ret int, err error) {
+	return 0, f.pathError("write", fs.ErrReadOnly)
+}
+
+func (f *file) pathError(op string, err error) error {
+	return &fs.PathError{Op: op, Path: f.name, Err: err}
+}
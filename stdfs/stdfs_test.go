@@ -0,0 +1,155 @@
+package stdfs_test
+
+import (
+	"archive/zip"
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"testing/fstest"
+	"time"
+
+	"github.com/daaku/go.fs"
+	conformance "github.com/daaku/go.fs/fstest"
+	"github.com/daaku/go.fs/limitfs"
+	"github.com/daaku/go.fs/memfs"
+	"github.com/daaku/go.fs/realfs"
+	"github.com/daaku/go.fs/stdfs"
+	"github.com/daaku/go.fs/zipfs"
+)
+
+var tree = map[string]string{
+	"foo.txt":       "foo",
+	"d/bar.txt":     "bar",
+	"d/e/baz.txt":   "baz",
+	"d/e/empty.txt": "",
+}
+
+var expected = []string{"foo.txt", "d", "d/bar.txt", "d/e", "d/e/baz.txt"}
+
+func TestMemFS(t *testing.T) {
+	t.Parallel()
+	files := make(map[string]fs.File)
+	for name, data := range tree {
+		files[name] = memfs.NewFile(name, 0644, time.Now(), []byte(data))
+	}
+	if err := fstest.TestFS(stdfs.FS(memfs.NewWithFiles(files)), expected...); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestZipFS(t *testing.T) {
+	t.Parallel()
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	for name, data := range tree {
+		w, err := zw.Create(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := w.Write([]byte(data)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	zr, err := zip.NewReader(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := fstest.TestFS(stdfs.FS(zipfs.New(zr)), expected...); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestRealFS(t *testing.T) {
+	t.Parallel()
+	err := fstest.TestFS(stdfs.FS(realfs.New()), "stdfs.go", "stdfs_test.go")
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestLimitFS(t *testing.T) {
+	t.Parallel()
+	dir, err := ioutil.TempDir("", "stdfs_test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	for name, data := range tree {
+		name = filepath.Join(dir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(name), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := ioutil.WriteFile(name, []byte(data), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	s := limitfs.New(limitfs.Config{Root: dir, Recursive: true}, realfs.New())
+	if err := fstest.TestFS(stdfs.FS(s), expected...); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestNew(t *testing.T) {
+	t.Parallel()
+	mapfs := make(fstest.MapFS)
+	for name, data := range tree {
+		mapfs[name] = &fstest.MapFile{Data: []byte(data), Mode: 0644}
+	}
+	s := stdfs.New(mapfs)
+	f, err := s.Open("/d/bar.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := ioutil.ReadAll(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(b) != "bar" {
+		t.Fatalf("was expecting bar, got %s", b)
+	}
+	d, err := s.Open("d")
+	if err != nil {
+		t.Fatal(err)
+	}
+	names, err := d.Readdirnames(-1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(names) != 2 || names[0] != "bar.txt" || names[1] != "e" {
+		t.Fatalf("did not find expected names, got %v", names)
+	}
+	if _, err := s.Open("missing"); !s.IsNotExist(err) {
+		t.Fatalf("was expecting is not exist error, got %v", err)
+	}
+	if _, err := s.Create("new"); err == nil {
+		t.Fatal("was expecting read-only error")
+	}
+
+	// and back again
+	if err := fstest.TestFS(stdfs.FS(s), expected...); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestConformance(t *testing.T) {
+	t.Parallel()
+	conformance.TestSystem(t, conformance.Config{
+		New: func(t *testing.T, files map[string]string) (fs.System, string) {
+			dir, err := ioutil.TempDir("", "stdfs_test")
+			if err != nil {
+				t.Fatal(err)
+			}
+			t.Cleanup(func() { os.RemoveAll(dir) })
+			if err := conformance.Populate(realfs.New(), dir, files); err != nil {
+				t.Fatal(err)
+			}
+			return stdfs.New(os.DirFS(dir)), "."
+		},
+		ReadOnly: true,
+	})
+}
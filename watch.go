@@ -0,0 +1,77 @@
+package fs
+
+import (
+	"errors"
+	"strings"
+)
+
+// ErrEventOverflow is sent on the Errors channel of a Watcher when Events were
+// dropped because they were not being consumed fast enough.
+var ErrEventOverflow = errors.New("fs: event queue overflow")
+
+// Op describes a set of changes to a file.
+type Op uint32
+
+// The changes reported by a Watcher.
+const (
+	OpCreate Op = 1 << iota // file was created, or renamed to this name
+	OpWrite                 // contents were modified
+	OpRemove                // file was removed
+	OpRename                // file was renamed away from this name
+	OpChmod                 // metadata like the mode or owner changed
+)
+
+var opNames = []struct {
+	op   Op
+	name string
+}{
+	{OpCreate, "CREATE"},
+	{OpWrite, "WRITE"},
+	{OpRemove, "REMOVE"},
+	{OpRename, "RENAME"},
+	{OpChmod, "CHMOD"},
+}
+
+func (op Op) String() string {
+	var names []string
+	for _, n := range opNames {
+		if op&n.op != 0 {
+			names = append(names, n.name)
+		}
+	}
+	return strings.Join(names, "|")
+}
+
+// An Event describes a change to a file.
+type Event struct {
+	Name string // in the same form as the name given to Watch
+	Op   Op
+}
+
+func (e Event) String() string {
+	return e.Op.String() + " " + e.Name
+}
+
+// A Watcher delivers Events about changes to files until it is closed.
+type Watcher interface {
+	// Events returns the channel Events are delivered on. It is closed when
+	// the Watcher is closed.
+	Events() <-chan Event
+
+	// Errors returns the channel errors are delivered on. It is closed when
+	// the Watcher is closed.
+	Errors() <-chan error
+
+	// Close stops watching and closes the channels.
+	Close() error
+}
+
+// A WatchSystem is a System that can notify about changes to files.
+type WatchSystem interface {
+	System
+
+	// Watch starts watching the named file or directory. For directories,
+	// changes to the directory and its immediate children are reported, and
+	// if recursive is true changes to all nested files are also reported.
+	Watch(name string, recursive bool) (Watcher, error)
+}
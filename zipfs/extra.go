@@ -0,0 +1,170 @@
+package zipfs
+
+import (
+	"archive/zip"
+	"encoding/binary"
+	"time"
+
+	"github.com/daaku/go.fs"
+	"github.com/daaku/go.fs/fsutil"
+)
+
+// Extra field tags with timestamps and ownership, see the APPNOTE and the
+// Info-ZIP extra field documentation.
+const (
+	extraNTFS          = 0x000a
+	extraUnixTimestamp = 0x5455
+	extraUnix1         = 0x5855
+	extraUnix2         = 0x7855
+	extraUnixN         = 0x7875
+)
+
+// Calls fn for each well formed field in the extra data.
+func extraFields(extra []byte, fn func(tag uint16, data []byte)) {
+	for len(extra) >= 4 {
+		tag := binary.LittleEndian.Uint16(extra)
+		size := int(binary.LittleEndian.Uint16(extra[2:]))
+		if len(extra) < 4+size {
+			return
+		}
+		fn(tag, extra[4:4+size])
+		extra = extra[4+size:]
+	}
+}
+
+// Times reports the access and creation times recorded in the extra fields of
+// the zip headers, in addition to the modification time. The change time is
+// never known.
+func (s system) Times(name string) (fs.Times, error) {
+	if f := s.files[fsutil.CleanRelative(name)]; f != nil {
+		return headerTimes(&f.FileHeader), nil
+	}
+	fi, err := s.Stat(name)
+	if err != nil {
+		return fs.Times{}, err
+	}
+	return fs.Times{Mtime: fi.ModTime()}, nil
+}
+
+func headerTimes(h *zip.FileHeader) fs.Times {
+	times := fs.Times{Mtime: h.Modified}
+	extraFields(h.Extra, func(tag uint16, data []byte) {
+		switch tag {
+		case extraNTFS:
+			ntfsTimes(data, &times)
+		case extraUnixTimestamp:
+			unixTimes(data, &times)
+		}
+	})
+	return times
+}
+
+// Parses the NTFS extra field, which has 100ns intervals since 1601 for the
+// modification, access and creation times.
+func ntfsTimes(data []byte, times *fs.Times) {
+	if len(data) < 4 {
+		return
+	}
+	for data = data[4:]; len(data) >= 4; {
+		tag := binary.LittleEndian.Uint16(data)
+		size := int(binary.LittleEndian.Uint16(data[2:]))
+		if len(data) < 4+size {
+			return
+		}
+		if tag == 1 && size == 24 {
+			times.Mtime = ntfsTime(binary.LittleEndian.Uint64(data[4:]))
+			times.Atime = ntfsTime(binary.LittleEndian.Uint64(data[12:]))
+			times.Btime = ntfsTime(binary.LittleEndian.Uint64(data[20:]))
+		}
+		data = data[4+size:]
+	}
+}
+
+func ntfsTime(t uint64) time.Time {
+	const epochDiff = 116444736000000000 // 1601 to 1970 in 100ns intervals
+	if t < epochDiff {
+		return time.Time{}
+	}
+	t -= epochDiff
+	return time.Unix(int64(t/1e7), int64(t%1e7)*100)
+}
+
+// Parses the extended timestamp extra field, which has flags followed by the
+// modification, access and creation times in seconds for the flags that are
+// set. The central directory usually only includes the modification time.
+func unixTimes(data []byte, times *fs.Times) {
+	if len(data) < 1 {
+		return
+	}
+	flags := data[0]
+	data = data[1:]
+	for ix, t := range []*time.Time{&times.Mtime, &times.Atime, &times.Btime} {
+		if flags&(1<<ix) == 0 {
+			continue
+		}
+		if len(data) < 4 {
+			return
+		}
+		*t = time.Unix(int64(int32(binary.LittleEndian.Uint32(data))), 0)
+		data = data[4:]
+	}
+}
+
+// Ident reports the owner recorded in the extra fields of the zip headers.
+// Entries have no Inode or Device.
+func (s system) Ident(name string) (fs.Ident, error) {
+	if f := s.files[fsutil.CleanRelative(name)]; f != nil {
+		return headerIdent(&f.FileHeader), nil
+	}
+	if _, err := s.Stat(name); err != nil {
+		return fs.Ident{}, err
+	}
+	return dirIdent, nil
+}
+
+// Directories may not have a header, so they never have an owner.
+var dirIdent = fs.Ident{Nlink: 2}
+
+func headerIdent(h *zip.FileHeader) fs.Ident {
+	ident := fs.Ident{Nlink: 1}
+	extraFields(h.Extra, func(tag uint16, data []byte) {
+		switch tag {
+		case extraUnixN:
+			unixNIdent(data, &ident)
+		case extraUnix2:
+			if len(data) >= 4 {
+				ident.UID = int(binary.LittleEndian.Uint16(data))
+				ident.GID = int(binary.LittleEndian.Uint16(data[2:]))
+			}
+		case extraUnix1:
+			if len(data) >= 12 {
+				ident.UID = int(binary.LittleEndian.Uint16(data[8:]))
+				ident.GID = int(binary.LittleEndian.Uint16(data[10:]))
+			}
+		}
+	})
+	return ident
+}
+
+// Parses the Info-ZIP new Unix extra field, which has a version followed by
+// the variable sized uid and gid, each preceded by its size.
+func unixNIdent(data []byte, ident *fs.Ident) {
+	if len(data) < 1 || data[0] != 1 {
+		return
+	}
+	data = data[1:]
+	var ids [2]int
+	for ix := range ids {
+		if len(data) < 1 || len(data) < 1+int(data[0]) || data[0] > 8 {
+			return
+		}
+		size := int(data[0])
+		var id uint64
+		for b := size; b > 0; b-- {
+			id = id<<8 | uint64(data[b])
+		}
+		ids[ix] = int(id)
+		data = data[1+size:]
+	}
+	ident.UID, ident.GID = ids[0], ids[1]
+}
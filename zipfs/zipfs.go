@@ -1,105 +1,427 @@
 // Package zipfs provides a zip file backed File System.
+//
+// Directories are synthesized from the names of the entries in the zip file,
+// so archives without explicit directory entries work as expected. Since zip
+// entries are compressed streams, Seek and ReadAt are emulated by reading
+// from the start of the entry as necessary.
 package zipfs
 
 import (
 	"archive/zip"
-	"errors"
 	"io"
+	"io/ioutil"
 	"os"
+	"path"
+	"time"
 
 	"github.com/daaku/go.fs"
 	"github.com/daaku/go.fs/fsutil"
 	"github.com/daaku/go.zipexe"
 )
 
+// Provides the write methods which are not supported by either files or
+// directories.
+type readOnly struct {
+	name string
+}
+
+func (r readOnly) Chmod(mode os.FileMode) error {
+	return r.pathError("chmod", fs.ErrReadOnly)
+}
+
+func (r readOnly) Chown(uid, gid int) error {
+	return r.pathError("chown", fs.ErrReadOnly)
+}
+
+func (r readOnly) Sync() (err error) {
+	return nil
+}
+
+func (r readOnly) Truncate(size int64) error {
+	return r.pathError("truncate", fs.ErrReadOnly)
+}
+
+func (r readOnly) Write(b []byte) (ret int, err error) {
+	return 0, r.pathError("write", fs.ErrReadOnly)
+}
+
+func (r readOnly) WriteAt(b []byte, off int64) (ret int, err error) {
+	return 0, r.pathError("write", fs.ErrReadOnly)
+}
+
+func (r readOnly) WriteString(s string) (ret int, err error) {
+	return 0, r.pathError("write", fs.ErrReadOnly)
+}
+
+func (r readOnly) pathError(op string, err error) error {
+	return &fs.PathError{Op: op, Path: r.name, Err: err}
+}
+
 type file struct {
-	io.ReadCloser
+	readOnly
 	*zip.File
+	rc     io.ReadCloser // opened lazily on the first Read
+	rcOff  int64         // offset of rc in the uncompressed data
+	off    int64         // offset for the next Read
+	closed bool
 }
 
-func (f *file) Stat() (os.FileInfo, error) {
-	return f.FileInfo(), nil
+func (f *file) Close() error {
+	if f.closed {
+		return f.pathError("close", fs.ErrClosed)
+	}
+	f.closed = true
+	if f.rc != nil {
+		return f.rc.Close()
+	}
+	return nil
 }
 
-func (f *file) Chmod(mode os.FileMode) error {
-	return errors.New("zipfs: Chmod not supported on file")
+func (f *file) Ident() (fs.Ident, error) {
+	if f.closed {
+		return fs.Ident{}, f.pathError("ident", fs.ErrClosed)
+	}
+	return headerIdent(&f.FileHeader), nil
 }
 
-func (f *file) Chown(uid, gid int) error {
-	return errors.New("zipfs: Chown not supported on file")
+func (f *file) Stat() (os.FileInfo, error) {
+	if f.closed {
+		return nil, f.pathError("stat", fs.ErrClosed)
+	}
+	return f.FileInfo(), nil
 }
 
-func (f *file) OwnerGID() (int, error) {
-	return 0, errors.New("zipfs: OwnerGID not supported on file")
-}
+func (f *file) Read(b []byte) (n int, err error) {
+	if f.closed {
+		return 0, f.pathError("read", fs.ErrClosed)
+	}
+	if f.off >= int64(f.UncompressedSize64) {
+		if len(b) == 0 {
+			return 0, nil
+		}
+		return 0, io.EOF
+	}
+
+	// going backwards requires starting over
+	if f.rc != nil && f.rcOff > f.off {
+		f.rc.Close()
+		f.rc = nil
+	}
+	if f.rc == nil {
+		if f.rc, err = f.Open(); err != nil {
+			return 0, err
+		}
+		f.rcOff = 0
+	}
+	if f.rcOff < f.off {
+		skipped, err := io.CopyN(ioutil.Discard, f.rc, f.off-f.rcOff)
+		f.rcOff += skipped
+		if err != nil {
+			return 0, err
+		}
+	}
 
-func (f *file) OwnerUID() (int, error) {
-	return 0, errors.New("zipfs: OwnerUID not supported on file")
+	n, err = f.rc.Read(b)
+	f.off += int64(n)
+	f.rcOff += int64(n)
+	return n, err
 }
 
+// ReadAt reads the entry from the start using a new reader, and does not
+// affect the offset used by Read.
 func (f *file) ReadAt(b []byte, off int64) (n int, err error) {
-	return 0, errors.New("zipfs: ReadAt not supported on file")
+	if f.closed {
+		return 0, f.pathError("read", fs.ErrClosed)
+	}
+	if off < 0 {
+		return 0, f.pathError("read", fs.ErrInvalid)
+	}
+	if off >= int64(f.UncompressedSize64) {
+		return 0, io.EOF
+	}
+	rc, err := f.Open()
+	if err != nil {
+		return 0, err
+	}
+	defer rc.Close()
+	if _, err := io.CopyN(ioutil.Discard, rc, off); err != nil {
+		return 0, err
+	}
+	n, err = io.ReadFull(rc, b)
+	if err == io.ErrUnexpectedEOF {
+		err = io.EOF
+	}
+	return n, err
 }
 
 func (f *file) Readdir(count int) ([]os.FileInfo, error) {
-	return nil, errors.New("zipfs: Readdir not supported on file")
+	return nil, f.pathError("readdir", fs.ErrNotDir)
 }
 
 func (f *file) Readdirnames(n int) (names []string, err error) {
-	return nil, errors.New("zipfs: Readdirnames not supported on file")
+	return nil, f.pathError("readdir", fs.ErrNotDir)
 }
 
+// Seek only records the offset, the actual work happens on the next Read.
 func (f *file) Seek(offset int64, whence int) (ret int64, err error) {
-	return 0, errors.New("zipfs: Seek not supported on file")
+	if f.closed {
+		return 0, f.pathError("seek", fs.ErrClosed)
+	}
+	switch whence {
+	case os.SEEK_SET:
+		ret = offset
+	case os.SEEK_CUR:
+		ret = f.off + offset
+	case os.SEEK_END:
+		ret = int64(f.UncompressedSize64) + offset
+	default:
+		return f.off, f.pathError("seek", fs.ErrInvalid)
+	}
+	if ret < 0 {
+		return f.off, f.pathError("seek", fs.ErrInvalid)
+	}
+	f.off = ret
+	return ret, nil
+}
+
+type dir struct {
+	readOnly
+	info   os.FileInfo
+	infos  []os.FileInfo
+	off    int
+	closed bool
 }
 
-func (f *file) Sync() (err error) {
+func (d *dir) Close() error {
+	if d.closed {
+		return d.pathError("close", fs.ErrClosed)
+	}
+	d.closed = true
 	return nil
 }
 
-func (f *file) Truncate(size int64) error {
-	return errors.New("zipfs: Truncate not supported on file")
+func (d *dir) Ident() (fs.Ident, error) {
+	if d.closed {
+		return fs.Ident{}, d.pathError("ident", fs.ErrClosed)
+	}
+	return dirIdent, nil
 }
 
-func (f *file) Write(b []byte) (ret int, err error) {
-	return 0, errors.New("zipfs: Write not supported on file")
+func (d *dir) Stat() (os.FileInfo, error) {
+	if d.closed {
+		return nil, d.pathError("stat", fs.ErrClosed)
+	}
+	return d.info, nil
 }
 
-func (f *file) WriteAt(b []byte, off int64) (ret int, err error) {
-	return 0, errors.New("zipfs: WriteAt not supported on file")
+func (d *dir) Read(b []byte) (n int, err error) {
+	return 0, d.pathError("read", fs.ErrIsDir)
 }
 
-func (f *file) WriteString(s string) (ret int, err error) {
-	return 0, errors.New("zipfs: WriteString not supported on file")
+func (d *dir) ReadAt(b []byte, off int64) (n int, err error) {
+	return 0, d.pathError("read", fs.ErrIsDir)
+}
+
+func (d *dir) Seek(offset int64, whence int) (ret int64, err error) {
+	return 0, d.pathError("seek", fs.ErrIsDir)
+}
+
+func (d *dir) Readdir(count int) ([]os.FileInfo, error) {
+	if d.closed {
+		return nil, d.pathError("readdir", fs.ErrClosed)
+	}
+	rest := d.infos[d.off:]
+	if count <= 0 {
+		d.off = len(d.infos)
+		return rest, nil
+	}
+	if len(rest) == 0 {
+		return nil, io.EOF
+	}
+	if count > len(rest) {
+		count = len(rest)
+	}
+	d.off += count
+	return rest[:count], nil
+}
+
+func (d *dir) Readdirnames(n int) (names []string, err error) {
+	fis, err := d.Readdir(n)
+	names = make([]string, len(fis))
+	for ix, fi := range fis {
+		names[ix] = fi.Name()
+	}
+	return names, err
+}
+
+// FileInfo for directories without an explicit entry in the zip file.
+type dirInfo string
+
+func (d dirInfo) Name() string       { return path.Base(string(d)) }
+func (d dirInfo) Size() int64        { return 0 }
+func (d dirInfo) Mode() os.FileMode  { return os.ModeDir | 0555 }
+func (d dirInfo) ModTime() time.Time { return time.Time{} }
+func (d dirInfo) IsDir() bool        { return true }
+func (d dirInfo) Sys() interface{}   { return nil }
+
+type dirEntry struct {
+	info     os.FileInfo
+	children map[string]os.FileInfo
+}
+
+// Returns the FileInfos for the directory sorted by name.
+func (d *dirEntry) list() []os.FileInfo {
+	infos := make([]os.FileInfo, 0, len(d.children))
+	for _, fi := range d.children {
+		infos = append(infos, fi)
+	}
+	fsutil.SortByName(infos)
+	return infos
 }
 
 type system struct {
-	zipReader *zip.Reader
+	files map[string]*zip.File
+	dirs  map[string]*dirEntry
 }
 
 func (s system) Open(name string) (fs.File, error) {
-	for _, f := range s.zipReader.File {
-		if f.Name == name {
-			rc, err := f.Open()
-			if err != nil {
-				return nil, err
-			}
-			return &file{
-				ReadCloser: rc,
-				File:       f,
-			}, nil
+	name = fsutil.CleanRelative(name)
+	if f := s.files[name]; f != nil {
+		return &file{readOnly: readOnly{name: name}, File: f}, nil
+	}
+	if d := s.dirs[name]; d != nil {
+		return &dir{
+			readOnly: readOnly{name: name},
+			info:     d.info,
+			infos:    d.list(),
+		}, nil
+	}
+	return nil, &fs.PathError{Op: "open", Path: name, Err: fs.ErrNotExist}
+}
+
+// Stat uses the zip headers and does not decompress the file.
+func (s system) Stat(name string) (os.FileInfo, error) {
+	name = fsutil.CleanRelative(name)
+	if f := s.files[name]; f != nil {
+		return f.FileInfo(), nil
+	}
+	if d := s.dirs[name]; d != nil {
+		return d.info, nil
+	}
+	return nil, &fs.PathError{Op: "stat", Path: name, Err: fs.ErrNotExist}
+}
+
+func (s system) ReadDir(name string) ([]os.FileInfo, error) {
+	name = fsutil.CleanRelative(name)
+	if d := s.dirs[name]; d != nil {
+		return d.list(), nil
+	}
+	if s.files[name] != nil {
+		return nil, &fs.PathError{Op: "readdir", Path: name, Err: fs.ErrNotDir}
+	}
+	return nil, &fs.PathError{Op: "readdir", Path: name, Err: fs.ErrNotExist}
+}
+
+// ReadFile decompresses the entire file into a buffer of the right size.
+func (s system) ReadFile(name string) ([]byte, error) {
+	name = fsutil.CleanRelative(name)
+	f := s.files[name]
+	if f == nil {
+		if s.dirs[name] != nil {
+			return nil, &fs.PathError{Op: "read", Path: name, Err: fs.ErrIsDir}
 		}
+		return nil, &fs.PathError{Op: "open", Path: name, Err: fs.ErrNotExist}
+	}
+	rc, err := f.Open()
+	if err != nil {
+		return nil, err
+	}
+	defer rc.Close()
+	data := make([]byte, f.UncompressedSize64)
+	if _, err := io.ReadFull(rc, data); err != nil {
+		return nil, err
 	}
-	return nil, fsutil.NewErrNotFound(name)
+	return data, nil
 }
 
 func (s system) IsNotExist(err error) bool {
 	return fsutil.IsNotExist(err)
 }
 
+func (s system) Create(name string) (fs.File, error) {
+	return nil, &fs.PathError{Op: "open", Path: name, Err: fs.ErrReadOnly}
+}
+
+func (s system) OpenFile(name string, flag int, perm os.FileMode) (fs.File, error) {
+	if fsutil.WantsWrite(flag) {
+		return nil, &fs.PathError{Op: "open", Path: name, Err: fs.ErrReadOnly}
+	}
+	return s.Open(name)
+}
+
+func (s system) Mkdir(name string, perm os.FileMode) error {
+	return &fs.PathError{Op: "mkdir", Path: name, Err: fs.ErrReadOnly}
+}
+
+func (s system) MkdirAll(path string, perm os.FileMode) error {
+	return &fs.PathError{Op: "mkdir", Path: path, Err: fs.ErrReadOnly}
+}
+
+func (s system) Remove(name string) error {
+	return &fs.PathError{Op: "remove", Path: name, Err: fs.ErrReadOnly}
+}
+
+func (s system) RemoveAll(path string) error {
+	return &fs.PathError{Op: "remove", Path: path, Err: fs.ErrReadOnly}
+}
+
+func (s system) Rename(oldname, newname string) error {
+	return &fs.LinkError{Op: "rename", Old: oldname, New: newname, Err: fs.ErrReadOnly}
+}
+
+// Returns the entry for the named directory, creating it and its parents as
+// necessary.
+func (s system) dir(name string) *dirEntry {
+	if d := s.dirs[name]; d != nil {
+		return d
+	}
+	d := &dirEntry{
+		info:     dirInfo(name),
+		children: make(map[string]os.FileInfo),
+	}
+	s.dirs[name] = d
+	if name != "." {
+		s.dir(path.Dir(name)).children[path.Base(name)] = d.info
+	}
+	return d
+}
+
 // Open a file system using the given zip.Reader.
 func New(zr *zip.Reader) fs.System {
-	return system{zipReader: zr}
+	s := system{
+		files: make(map[string]*zip.File),
+		dirs:  make(map[string]*dirEntry),
+	}
+	s.dir(".")
+	for _, f := range zr.File {
+		name := fsutil.CleanRelative(f.Name)
+		if name == "." {
+			continue
+		}
+		if f.FileInfo().IsDir() {
+			// explicit entries provide the mode and modification time
+			d := s.dir(name)
+			d.info = f.FileInfo()
+			s.dir(path.Dir(name)).children[path.Base(name)] = d.info
+			continue
+		}
+		if s.files[name] != nil {
+			continue
+		}
+		s.files[name] = f
+		s.dir(path.Dir(name)).children[path.Base(name)] = f.FileInfo()
+	}
+	return s
 }
 
 // Opens the named zip file as a fs.System.
@@ -0,0 +1,358 @@
+package zipfs_test
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"io"
+	"io/ioutil"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/daaku/go.fs"
+	"github.com/daaku/go.fs/fstest"
+	"github.com/daaku/go.fs/fsutil"
+	"github.com/daaku/go.fs/zipfs"
+)
+
+func newZipSystem(t *testing.T, files map[string]string) fs.System {
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	for name, data := range files {
+		w, err := zw.Create(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := w.Write([]byte(data)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	zr, err := zip.NewReader(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return zipfs.New(zr)
+}
+
+func TestOpen(t *testing.T) {
+	t.Parallel()
+	s := newZipSystem(t, map[string]string{"d/foo": "bar"})
+	f, err := s.Open("d/foo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	b, err := ioutil.ReadAll(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(b) != "bar" {
+		t.Fatalf("was expecting bar, got %s", b)
+	}
+	if _, err := s.Open("d/baz"); !s.IsNotExist(err) {
+		t.Fatalf("was expecting is not exist error, got %v", err)
+	}
+}
+
+func TestStat(t *testing.T) {
+	t.Parallel()
+	s := newZipSystem(t, map[string]string{"d/foo": "bar"})
+	fi, err := fsutil.Stat(s, "d/foo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fi.Name() != "foo" || fi.Size() != 3 {
+		t.Fatalf("did not find expected file info: %s %d", fi.Name(), fi.Size())
+	}
+	if _, err := fsutil.Stat(s, "d/baz"); !s.IsNotExist(err) {
+		t.Fatalf("was expecting is not exist error, got %v", err)
+	}
+}
+
+func TestReadOnly(t *testing.T) {
+	t.Parallel()
+	s := newZipSystem(t, map[string]string{"d/foo": "bar"})
+	if _, err := s.Create("d/baz"); !fsutil.IsReadOnly(err) {
+		t.Fatalf("was expecting read-only error, got %v", err)
+	}
+	if err := s.Remove("d/foo"); !fsutil.IsReadOnly(err) {
+		t.Fatalf("was expecting read-only error, got %v", err)
+	}
+}
+
+func TestFileErrors(t *testing.T) {
+	t.Parallel()
+	s := newZipSystem(t, map[string]string{"d/foo": "bar"})
+	f, err := s.Open("d/foo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := f.Write([]byte("baz")); !errors.Is(err, fs.ErrReadOnly) {
+		t.Fatalf("was expecting ErrReadOnly, got %v", err)
+	}
+	if _, err := f.Readdir(0); !errors.Is(err, fs.ErrNotDir) {
+		t.Fatalf("was expecting ErrNotDir, got %v", err)
+	}
+	if err := fsutil.Lock(f); !errors.Is(err, fs.ErrNotSupported) {
+		t.Fatalf("was expecting ErrNotSupported, got %v", err)
+	}
+	if _, err := fsutil.Getxattr(s, "d/foo", "user.a"); !errors.Is(err, fs.ErrNotSupported) {
+		t.Fatalf("was expecting ErrNotSupported, got %v", err)
+	}
+	f.Close()
+	if _, err := f.Read(nil); !errors.Is(err, fs.ErrClosed) {
+		t.Fatalf("was expecting ErrClosed, got %v", err)
+	}
+	d, err := s.Open("d")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := d.Read(nil); !errors.Is(err, fs.ErrIsDir) {
+		t.Fatalf("was expecting ErrIsDir, got %v", err)
+	}
+}
+
+func TestSynthesizedDirs(t *testing.T) {
+	t.Parallel()
+	s := newZipSystem(t, map[string]string{
+		"a/b/c": "c",
+		"a/d":   "d",
+		"e/":    "",
+		"f":     "f",
+	})
+	for name, expected := range map[string][]string{
+		".":   {"a", "e", "f"},
+		"/":   {"a", "e", "f"},
+		"a":   {"b", "d"},
+		"a/b": {"c"},
+		"e":   {},
+	} {
+		infos, err := fsutil.ReadDir(s, name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var names []string
+		for _, fi := range infos {
+			names = append(names, fi.Name())
+		}
+		if !slices.Equal(names, expected) {
+			t.Fatalf("%s: was expecting %v, got %v", name, expected, names)
+		}
+	}
+	fi, err := fsutil.Stat(s, "a/b")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !fi.IsDir() || fi.Name() != "b" {
+		t.Fatalf("was expecting directory b, got %s %s", fi.Name(), fi.Mode())
+	}
+	if _, err := fsutil.ReadDir(s, "f"); !errors.Is(err, fs.ErrNotDir) {
+		t.Fatalf("was expecting ErrNotDir, got %v", err)
+	}
+	if _, err := fsutil.ReadFile(s, "a"); !errors.Is(err, fs.ErrIsDir) {
+		t.Fatalf("was expecting ErrIsDir, got %v", err)
+	}
+
+	d, err := s.Open("a")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer d.Close()
+	names, err := d.Readdirnames(1)
+	if err != nil || len(names) != 1 || names[0] != "b" {
+		t.Fatalf("was expecting b, got %v %v", names, err)
+	}
+	names, err = d.Readdirnames(0)
+	if err != nil || len(names) != 1 || names[0] != "d" {
+		t.Fatalf("was expecting d, got %v %v", names, err)
+	}
+	if _, err := d.Readdirnames(1); err != io.EOF {
+		t.Fatalf("was expecting io.EOF, got %v", err)
+	}
+}
+
+func TestSeekReadAt(t *testing.T) {
+	t.Parallel()
+	s := newZipSystem(t, map[string]string{"foo": "0123456789"})
+	f, err := s.Open("foo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	read := func(size int) string {
+		t.Helper()
+		b := make([]byte, size)
+		n, err := f.Read(b)
+		if err != nil && err != io.EOF {
+			t.Fatal(err)
+		}
+		return string(b[:n])
+	}
+	seek := func(offset int64, whence int, expected int64) {
+		t.Helper()
+		ret, err := f.Seek(offset, whence)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if ret != expected {
+			t.Fatalf("was expecting offset %d, got %d", expected, ret)
+		}
+	}
+	if actual := read(4); actual != "0123" {
+		t.Fatalf("was expecting 0123, got %s", actual)
+	}
+	seek(2, io.SeekCurrent, 6)
+	if actual := read(2); actual != "67" {
+		t.Fatalf("was expecting 67, got %s", actual)
+	}
+	// going backwards starts over from the beginning of the entry
+	seek(1, io.SeekStart, 1)
+	if actual := read(2); actual != "12" {
+		t.Fatalf("was expecting 12, got %s", actual)
+	}
+	seek(-1, io.SeekEnd, 9)
+	if actual := read(4); actual != "9" {
+		t.Fatalf("was expecting 9, got %s", actual)
+	}
+	if _, err := f.Read(make([]byte, 1)); err != io.EOF {
+		t.Fatalf("was expecting io.EOF, got %v", err)
+	}
+	if _, err := f.Seek(-1, io.SeekStart); !errors.Is(err, fs.ErrInvalid) {
+		t.Fatalf("was expecting ErrInvalid, got %v", err)
+	}
+	if _, err := f.Seek(0, 99); !errors.Is(err, fs.ErrInvalid) {
+		t.Fatalf("was expecting ErrInvalid, got %v", err)
+	}
+
+	// ReadAt does not move the offset
+	seek(3, io.SeekStart, 3)
+	b := make([]byte, 4)
+	n, err := f.ReadAt(b, 8)
+	if n != 2 || err != io.EOF || string(b[:n]) != "89" {
+		t.Fatalf("was expecting 2 bytes and io.EOF, got %d %v %q", n, err, b[:n])
+	}
+	if n, err := f.ReadAt(b, 2); n != 4 || err != nil || string(b) != "2345" {
+		t.Fatalf("was expecting 2345, got %d %v %q", n, err, b[:n])
+	}
+	if _, err := f.ReadAt(b, 10); err != io.EOF {
+		t.Fatalf("was expecting io.EOF, got %v", err)
+	}
+	if _, err := f.ReadAt(b, -1); !errors.Is(err, fs.ErrInvalid) {
+		t.Fatalf("was expecting ErrInvalid, got %v", err)
+	}
+	if actual := read(2); actual != "34" {
+		t.Fatalf("was expecting 34, got %s", actual)
+	}
+}
+
+func TestConformance(t *testing.T) {
+	t.Parallel()
+	fstest.TestSystem(t, fstest.Config{
+		New: func(t *testing.T, files map[string]string) (fs.System, string) {
+			return newZipSystem(t, files), "."
+		},
+		ReadOnly: true,
+	})
+}
+
+func TestTimes(t *testing.T) {
+	t.Parallel()
+	mtime := time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC)
+	atime := mtime.Add(time.Hour)
+	btime := mtime.Add(-time.Hour)
+	ntfs := func(t time.Time) uint64 {
+		return uint64(t.UnixNano()/100) + 116444736000000000
+	}
+	extra := make([]byte, 36)
+	binary.LittleEndian.PutUint16(extra, 0x000a)
+	binary.LittleEndian.PutUint16(extra[2:], 32)
+	binary.LittleEndian.PutUint16(extra[8:], 1)
+	binary.LittleEndian.PutUint16(extra[10:], 24)
+	binary.LittleEndian.PutUint64(extra[12:], ntfs(mtime))
+	binary.LittleEndian.PutUint64(extra[20:], ntfs(atime))
+	binary.LittleEndian.PutUint64(extra[28:], ntfs(btime))
+
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	if _, err := zw.CreateHeader(&zip.FileHeader{Name: "foo", Modified: mtime, Extra: extra}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := zw.CreateHeader(&zip.FileHeader{Name: "bar", Modified: mtime}); err != nil {
+		t.Fatal(err)
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	zr, err := zip.NewReader(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := zipfs.New(zr)
+
+	times, err := fsutil.Times(s, "foo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !times.Mtime.Equal(mtime) || !times.Atime.Equal(atime) || !times.Btime.Equal(btime) || !times.Ctime.IsZero() {
+		t.Fatalf("did not find expected times, got %v", times)
+	}
+	times, err = fsutil.Times(s, "bar")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !times.Mtime.Equal(mtime) || !times.Atime.IsZero() {
+		t.Fatalf("did not find expected times, got %v", times)
+	}
+	if _, err := fsutil.Times(s, "missing"); !s.IsNotExist(err) {
+		t.Fatalf("was expecting is not exist error, got %v", err)
+	}
+}
+
+func TestIdent(t *testing.T) {
+	t.Parallel()
+	extra := []byte{0x75, 0x78, 11, 0, 1, 4, 0xe8, 0x03, 0, 0, 4, 0xe9, 0x03, 0, 0}
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	if _, err := zw.CreateHeader(&zip.FileHeader{Name: "d/foo", Extra: extra}); err != nil {
+		t.Fatal(err)
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	zr, err := zip.NewReader(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := zipfs.New(zr)
+
+	expected := fs.Ident{UID: 1000, GID: 1001, Nlink: 1}
+	ident, err := fsutil.Ident(s, "d/foo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ident != expected {
+		t.Fatalf("was expecting %+v, got %+v", expected, ident)
+	}
+	f, err := s.Open("d/foo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	if ident, err = f.Ident(); err != nil {
+		t.Fatal(err)
+	}
+	if ident != expected {
+		t.Fatalf("was expecting %+v, got %+v", expected, ident)
+	}
+	if ident, err = fsutil.Ident(s, "d"); err != nil {
+		t.Fatal(err)
+	}
+	if ident.UID != 0 || ident.Nlink != 2 {
+		t.Fatalf("did not find expected directory ident, got %+v", ident)
+	}
+}